@@ -0,0 +1,49 @@
+// Command botsim plays bots against each other in-process and prints win
+// rates and how long the games took. With two levels it plays one series,
+// with more a round robin.
+//
+//	go run ./cmd/botsim -games 1000 easy hard
+//	go run ./cmd/botsim -csv easy medium hard
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lesta-battleship/server-core/internal/sim"
+	"os"
+)
+
+func main() {
+	games := flag.Int("games", 1000, "games per pair of bots")
+	seed := flag.Int64("seed", 1, "seed for fleets and bots")
+	mode := flag.String("mode", "", "game mode of the rooms")
+	asCSV := flag.Bool("csv", false, "write CSV instead of a table")
+	flag.Parse()
+	if flag.NArg() < 2 {
+		fmt.Fprintln(os.Stderr, "usage: botsim [-games n] [-seed n] [-mode mode] [-csv] level level...")
+		os.Exit(2)
+	}
+
+	players := make([]sim.Player, flag.NArg())
+	for i, level := range flag.Args() {
+		players[i] = sim.BotPlayer(level)
+	}
+	reports, err := sim.Tournament(players, *games, *seed, *mode)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *asCSV {
+		err = sim.WriteCSV(os.Stdout, reports)
+	} else {
+		err = sim.WriteTable(os.Stdout, reports)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,68 @@
+// Command itembalance uses a catalogue item many times on random legal fleets
+// and prints how much it revealed, hit and wasted out of bounds. It runs
+// entirely offline.
+//
+//	go run ./cmd/itembalance -runs 10000 -params x=0..9,y=0..9 "Крест Нахимова"
+//	go run ./cmd/itembalance -sweep -csv -params x=0..9,y=4,direction=1..2 "Ладья"
+package main
+
+import (
+	"flag"
+	"fmt"
+	"lesta-battleship/server-core/internal/balance"
+	"lesta-battleship/server-core/internal/items"
+	"os"
+)
+
+func main() {
+	cataloguePath := flag.String("catalogue", "internal/items/Items_logic2.json", "item catalogue file")
+	runs := flag.Int("runs", 1000, "runs per param set")
+	seed := flag.Int64("seed", 1, "seed for fleets and RAND")
+	paramList := flag.String("params", "x=0..9,y=0..9", "params as name=value or name=min..max, comma separated")
+	sweep := flag.Bool("sweep", false, "run every combination of param values instead of drawing them at random")
+	asCSV := flag.Bool("csv", false, "write CSV instead of a table")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: itembalance [-catalogue file] [-runs n] [-seed n] [-params spec] [-sweep] [-csv] item")
+		os.Exit(2)
+	}
+
+	catalogue, err := items.LoadCatalogueFile(*cataloguePath)
+	if err != nil {
+		fatal(err)
+	}
+	item, ok := catalogue.ItemByName(flag.Arg(0))
+	if !ok {
+		fatal(fmt.Errorf("no item %q in %s", flag.Arg(0), *cataloguePath))
+	}
+	params, err := balance.ParseParams(*paramList)
+	if err != nil {
+		fatal(err)
+	}
+
+	reports, err := balance.Run(balance.Config{
+		Item:      item,
+		Catalogue: catalogue.Items,
+		Runs:      *runs,
+		Seed:      *seed,
+		Params:    params,
+		Sweep:     *sweep,
+	})
+	if err != nil {
+		fatal(err)
+	}
+
+	if *asCSV {
+		err = balance.WriteCSV(os.Stdout, reports)
+	} else {
+		err = balance.WriteTable(os.Stdout, reports)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
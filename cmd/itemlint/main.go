@@ -0,0 +1,63 @@
+// Command itemlint statically checks an item catalogue file and exits with a
+// non-zero status if it contains errors.
+//
+//	go run ./cmd/itemlint internal/items/Items_logic2.json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"lesta-battleship/server-core/internal/items"
+	"os"
+)
+
+func main() {
+	strict := flag.Bool("strict", false, "treat warnings as errors")
+	mode := flag.String("mode", "", "also report actions the game mode disables")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: itemlint [-strict] [-mode mode] catalogue.json")
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	catalogue, err := items.LoadCatalogue(data)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			fmt.Printf("%s:%d: error: %v\n", path, lineOf(data, syntaxErr.Offset), err)
+		} else {
+			fmt.Printf("%s: error: %v\n", path, err)
+		}
+		os.Exit(1)
+	}
+
+	diags := catalogue.ValidateMode(*mode)
+	failed := items.HasErrors(diags)
+	for _, d := range diags {
+		fmt.Printf("%s:%d: %s: %s: %s\n", path, d.Line, d.Severity, d.Item, d.Msg)
+		if *strict && d.Severity == items.SeverityWarning {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func lineOf(data []byte, offset int64) int {
+	line := 1
+	for i := int64(0); i < offset && i < int64(len(data)); i++ {
+		if data[i] == '\n' {
+			line++
+		}
+	}
+	return line
+}
@@ -0,0 +1,148 @@
+// Command itemtrace runs a catalogue item against a board read from a text
+// file and prints every step of the script with the board before and after.
+//
+//	go run ./cmd/itemtrace -board board.txt -params x=5,y=5 "Крест Нахимова"
+//
+// The board file holds ten rows of ten cells: ~ water, S ship, X hit ship,
+// o miss, . revealed water. Without -board the item runs on an empty board.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/rng"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	cataloguePath := flag.String("catalogue", "internal/items/Items_logic2.json", "item catalogue file")
+	boardPath := flag.String("board", "", "board file (default: empty board)")
+	paramList := flag.String("params", "", "script params as key=value,key=value")
+	seed := flag.Int64("seed", 1, "seed for RAND")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: itemtrace [-catalogue file] [-board file] [-params k=v,...] [-seed n] item")
+		os.Exit(2)
+	}
+
+	catalogue, err := items.LoadCatalogueFile(*cataloguePath)
+	if err != nil {
+		fatal(err)
+	}
+	item, ok := catalogue.ItemByName(flag.Arg(0))
+	if !ok {
+		fatal(fmt.Errorf("no item %q in %s", flag.Arg(0), *cataloguePath))
+	}
+	state := game.NewGameState()
+	if *boardPath != "" {
+		data, err := os.ReadFile(*boardPath)
+		if err != nil {
+			fatal(err)
+		}
+		if state, err = game.ParseBoard(string(data)); err != nil {
+			fatal(fmt.Errorf("%s: %w", *boardPath, err))
+		}
+	}
+	params, err := parseParams(*paramList)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%s (%s), seed %d, params %v\n\nbefore:\n%s\n", item.Name, item.Kind, *seed, params, game.FormatBoard(state))
+
+	trace := &items.Trace{}
+	rt := &items.Runtime{RNG: rng.New(*seed), Trace: trace}
+	result, runErr := rt.UseItem(context.Background(), item.ID, state, catalogue.Items, params)
+
+	for i, s := range trace.Steps {
+		printStep(i+1, s)
+	}
+	fmt.Println()
+	if runErr != nil {
+		fmt.Printf("error: %v\nthe script was rolled back\n\n", runErr)
+	} else if result.EndsTurn {
+		fmt.Print("the item ends the turn\n\n")
+	}
+	fmt.Printf("after:\n%s", game.FormatBoard(state))
+	if runErr != nil {
+		os.Exit(1)
+	}
+}
+
+func printStep(n int, s items.TraceStep) {
+	indent := strings.Repeat("  ", s.Depth)
+	line := ""
+	if s.Line > 0 {
+		line = fmt.Sprintf(" (line %d)", s.Line)
+	}
+	fmt.Printf("%s%d. %s%s\n", indent, n, s.Action, line)
+	if len(s.RawArgs) > 0 {
+		fmt.Printf("%s   args: %s\n", indent, formatArgs(s.RawArgs))
+	}
+	if len(s.Args) > 0 {
+		fmt.Printf("%s   eval: %s\n", indent, formatArgs(s.Args))
+	}
+	for _, d := range s.Draws {
+		fmt.Printf("%s   rand: %d of %d\n", indent, d.Value, d.N)
+	}
+	for _, c := range s.Changes {
+		fmt.Printf("%s   cell (%d,%d): %s -> %s\n", indent, c.Cell.X, c.Cell.Y, c.From, c.To)
+	}
+	if s.Outcome != "" {
+		fmt.Printf("%s   outcome: %s\n", indent, s.Outcome)
+	}
+	if s.Err != nil {
+		fmt.Printf("%s   error: %v\n", indent, s.Err)
+	}
+}
+
+func formatArgs(args map[string]interface{}) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		switch v := args[k].(type) {
+		case float64, int:
+			parts[i] = fmt.Sprintf("%s=%v", k, v)
+		default:
+			raw, _ := json.Marshal(v)
+			parts[i] = fmt.Sprintf("%s=%s", k, raw)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func parseParams(list string) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if list == "" {
+		return params, nil
+	}
+	for _, pair := range strings.Split(list, ",") {
+		key, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid param %q, want key=value", pair)
+		}
+		key = strings.TrimSpace(key)
+		if f, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+			params[key] = f
+		} else {
+			params[key] = strings.TrimSpace(val)
+		}
+	}
+	return params, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(2)
+}
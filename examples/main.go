@@ -1,13 +1,30 @@
 package main
 
 import (
+	"context"
 	"lesta-battleship/server-core/internal/api"
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/transaction"
 	"lesta-battleship/server-core/internal/ws"
+	"log"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	transaction.Use(transaction.LoggingHook())
+
+	var provider items.Provider = items.FileProvider{Path: "internal/items/Items_logic2.json"}
+	if url := os.Getenv("ITEMS_URL"); url != "" {
+		provider = items.NewHTTPProvider(url, 5*time.Second)
+	}
+	ws.Catalogue = items.NewCache(provider)
+	if err := ws.Catalogue.Reload(context.Background()); err != nil {
+		log.Fatal("[ITEMS] Cannot load catalogue: ", err)
+	}
+	go ws.Catalogue.Watch(context.Background(), time.Minute)
 
 	r := gin.Default()
 	r.POST("/start-match", api.StartMatch)
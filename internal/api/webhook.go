@@ -1,10 +1,10 @@
 package api
 
 import (
-	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/bot"
 	"lesta-battleship/server-core/internal/match"
+	"lesta-battleship/server-core/internal/ws"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -15,18 +15,58 @@ func StartMatch(c *gin.Context) {
 		Player1 string `json:"player1"`
 		Player2 string `json:"player2"`
 		Mode    string `json:"mode"`
+		Seed    int64  `json:"seed"`
+		// TurnRule overrides the turn rule of the mode: alternate or
+		// continue_on_hit.
+		TurnRule match.TurnRule `json:"turn_rule"`
+
+		Player1Loadout match.Loadout `json:"player1_loadout"`
+		Player2Loadout match.Loadout `json:"player2_loadout"`
+
+		// Player1Bot and Player2Bot name the bot level that plays for the
+		// player, if any: easy, medium or hard.
+		Player1Bot string `json:"player1_bot"`
+		Player2Bot string `json:"player2_bot"`
 	}
 	if err := c.ShouldBindJSON(&payload); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	room := &match.GameRoom{
-		RoomID:    payload.RoomID,
-		Mode:      payload.Mode,
-		Player1:   &match.PlayerConn{ID: payload.Player1, State: game.NewGameState()},
-		Player2:   &match.PlayerConn{ID: payload.Player2, State: game.NewGameState()},
-		Status:    "waiting",
-		CreatedAt: time.Now(),
+	if payload.Player1Bot != "" && payload.Player2Bot != "" {
+		// Nobody would ever send ready; bot against bot is for cmd/botsim.
+		c.JSON(http.StatusBadRequest, gin.H{"error": "at least one player must be human"})
+		return
+	}
+	if payload.TurnRule != "" && !payload.TurnRule.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown turn rule: " + string(payload.TurnRule)})
+		return
+	}
+	room := match.NewGameRoom(match.Options{
+		RoomID:   payload.RoomID,
+		Mode:     payload.Mode,
+		Player1:  payload.Player1,
+		Player2:  payload.Player2,
+		Loadout1: payload.Player1Loadout,
+		Loadout2: payload.Player2Loadout,
+		Seed:     payload.Seed,
+		TurnRule: payload.TurnRule,
+	})
+	ws.Attach(room)
+	for _, b := range []struct{ player, level string }{
+		{payload.Player1, payload.Player1Bot},
+		{payload.Player2, payload.Player2Bot},
+	} {
+		if b.level == "" {
+			continue
+		}
+		agent, err := bot.New(b.level, room.RNG.Fork())
+		if err == nil {
+			err = room.AddAgent(b.player, agent)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 	match.Rooms.Store(payload.RoomID, room)
 	c.JSON(http.StatusOK, gin.H{"status": "created"})
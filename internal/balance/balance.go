@@ -0,0 +1,264 @@
+// Package balance estimates how strong an item is by using it many times on
+// random legal fleets and summarising what it achieved.
+package balance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/rng"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Range is an inclusive range of integer param values. A fixed param has
+// Min == Max.
+type Range struct {
+	Min, Max int
+}
+
+// Config describes a simulation. In random mode every run draws each param
+// uniformly from its range and a single Report is produced; with Sweep set,
+// every combination of param values gets Runs runs and a Report of its own.
+type Config struct {
+	Item      items.Item
+	Catalogue []items.Item
+	Runs      int
+	Seed      int64
+	Params    map[string]Range
+	Sweep     bool
+}
+
+// Names of the reported metrics.
+const (
+	MetricRevealedShipCells = "revealed_ship_cells"
+	MetricHits              = "hits"
+	MetricOutOfBounds       = "out_of_bounds"
+)
+
+// Metric is the distribution of one per-run measurement.
+type Metric struct {
+	Name               string
+	Mean, StdDev       float64
+	Min, P50, P90, Max float64
+}
+
+// Report summarises the runs of one param set. Failed runs, where the script
+// returned an error and was rolled back, are left out of the metrics.
+type Report struct {
+	Item   string
+	Params map[string]int // the swept values; nil in random mode
+	Runs   int
+	Failed int
+	Shots  int
+	// HitProbability is the share of MAKE_SHOT actions that hit a ship.
+	HitProbability float64
+	Metrics        []Metric
+}
+
+// Run simulates cfg.
+func Run(cfg Config) ([]Report, error) {
+	if cfg.Runs <= 0 {
+		return nil, errors.New("runs must be positive")
+	}
+	for name, r := range cfg.Params {
+		if r.Min > r.Max {
+			return nil, fmt.Errorf("param %s: empty range %d..%d", name, r.Min, r.Max)
+		}
+	}
+	source := rng.New(cfg.Seed)
+
+	if !cfg.Sweep {
+		names := sortedNames(cfg.Params)
+		report, err := simulate(cfg, source, func() map[string]int {
+			params := map[string]int{}
+			for _, name := range names {
+				r := cfg.Params[name]
+				params[name] = r.Min + source.Intn(r.Max-r.Min+1)
+			}
+			return params
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []Report{report}, nil
+	}
+
+	var reports []Report
+	for _, params := range combinations(cfg.Params) {
+		report, err := simulate(cfg, source, func() map[string]int { return params })
+		if err != nil {
+			return nil, err
+		}
+		report.Params = params
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+type sample struct {
+	revealed, hits, outOfBounds float64
+}
+
+func simulate(cfg Config, source *rng.Source, params func() map[string]int) (Report, error) {
+	report := Report{Item: cfg.Item.Name, Runs: cfg.Runs}
+	var samples []sample
+	hits := 0
+	for i := 0; i < cfg.Runs; i++ {
+		state, err := game.RandomFleet(source)
+		if err != nil {
+			return Report{}, err
+		}
+		args := map[string]interface{}{}
+		for name, v := range params() {
+			args[name] = v
+		}
+
+		rt := &items.Runtime{RNG: source.Fork()}
+		result, err := rt.UseItem(context.Background(), cfg.Item.ID, state, cfg.Catalogue, args)
+		if err != nil {
+			report.Failed++
+			continue
+		}
+		s := measure(result)
+		samples = append(samples, s)
+		hits += int(s.hits)
+		for _, e := range result.Effects {
+			if e.Action == "MAKE_SHOT" {
+				report.Shots++
+			}
+		}
+	}
+	if report.Shots > 0 {
+		report.HitProbability = float64(hits) / float64(report.Shots)
+	}
+	report.Metrics = []Metric{
+		distribution(MetricRevealedShipCells, samples, func(s sample) float64 { return s.revealed }),
+		distribution(MetricHits, samples, func(s sample) float64 { return s.hits }),
+		distribution(MetricOutOfBounds, samples, func(s sample) float64 { return s.outOfBounds }),
+	}
+	return report, nil
+}
+
+func measure(result *items.Result) sample {
+	var s sample
+	revealed := map[game.Coord]bool{}
+	for _, e := range result.Effects {
+		switch e.Outcome {
+		case items.OutcomeRevealedShip, items.OutcomeHit:
+			for _, c := range e.Cells {
+				revealed[c] = true
+			}
+			if e.Outcome == items.OutcomeHit {
+				s.hits++
+			}
+		case items.OutcomeOutOfBounds:
+			s.outOfBounds++
+		}
+	}
+	s.revealed = float64(len(revealed))
+	return s
+}
+
+func distribution(name string, samples []sample, value func(sample) float64) Metric {
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		values[i] = value(s)
+	}
+	return Distribution(name, values)
+}
+
+// Distribution summarises values as a Metric. It sorts values in place.
+func Distribution(name string, values []float64) Metric {
+	m := Metric{Name: name}
+	if len(values) == 0 {
+		return m
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	sort.Float64s(values)
+	m.Mean = sum / float64(len(values))
+	for _, v := range values {
+		m.StdDev += (v - m.Mean) * (v - m.Mean)
+	}
+	m.StdDev = math.Sqrt(m.StdDev / float64(len(values)))
+	m.Min, m.Max = values[0], values[len(values)-1]
+	m.P50 = percentile(values, 0.5)
+	m.P90 = percentile(values, 0.9)
+	return m
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// combinations lists every assignment of values to the params, in sorted
+// param order.
+func combinations(params map[string]Range) []map[string]int {
+	combos := []map[string]int{{}}
+	for _, name := range sortedNames(params) {
+		r := params[name]
+		var next []map[string]int
+		for _, c := range combos {
+			for v := r.Min; v <= r.Max; v++ {
+				combo := make(map[string]int, len(c)+1)
+				for k, cv := range c {
+					combo[k] = cv
+				}
+				combo[name] = v
+				next = append(next, combo)
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+func sortedNames(params map[string]Range) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseParams reads params written as name=value or name=min..max, separated
+// by commas, e.g. "x=0..9,y=0..9,direction=1".
+func ParseParams(s string) (map[string]Range, error) {
+	params := map[string]Range{}
+	if strings.TrimSpace(s) == "" {
+		return params, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		name, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid param %q, want name=value or name=min..max", pair)
+		}
+		lo, hi, isRange := strings.Cut(val, "..")
+		if !isRange {
+			hi = lo
+		}
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+		to, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, fmt.Errorf("param %s: %w", name, err)
+		}
+		params[name] = Range{Min: from, Max: to}
+	}
+	return params, nil
+}
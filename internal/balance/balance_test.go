@@ -0,0 +1,126 @@
+package balance
+
+import (
+	"bytes"
+	"lesta-battleship/server-core/internal/items"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func loadItem(t *testing.T, name string) ([]items.Item, items.Item) {
+	t.Helper()
+	catalogue, err := items.LoadCatalogueFile("../items/Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, ok := catalogue.ItemByName(name)
+	if !ok {
+		t.Fatalf("no item %s", name)
+	}
+	return catalogue.Items, item
+}
+
+func TestRun_Random(t *testing.T) {
+	catalogue, cross := loadItem(t, "Крест Нахимова")
+	cfg := Config{Item: cross, Catalogue: catalogue, Runs: 300, Seed: 5, Params: map[string]Range{"x": {0, 9}, "y": {0, 9}}}
+	reports, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Failed != 0 || reports[0].Params != nil {
+		t.Fatalf("reports = %+v", reports)
+	}
+	metrics := map[string]Metric{}
+	for _, m := range reports[0].Metrics {
+		metrics[m.Name] = m
+	}
+	revealed := metrics[MetricRevealedShipCells]
+	if revealed.Mean <= 0 || revealed.Max > 5 || revealed.Min < 0 || revealed.P50 > revealed.P90 {
+		t.Errorf("revealed = %+v", revealed)
+	}
+	// A cross centred on a random cell sticks out of the field now and then.
+	if oob := metrics[MetricOutOfBounds]; oob.Mean <= 0 || oob.Max > 2 {
+		t.Errorf("out of bounds = %+v", oob)
+	}
+
+	again, _ := Run(cfg)
+	if !reflect.DeepEqual(reports, again) {
+		t.Error("the same seed gave different reports")
+	}
+}
+
+func TestRun_Sweep(t *testing.T) {
+	catalogue, cross := loadItem(t, "Крест Нахимова")
+	reports, err := Run(Config{Item: cross, Catalogue: catalogue, Runs: 20, Params: map[string]Range{"x": {0, 2}, "y": {0, 1}}, Sweep: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 6 {
+		t.Fatalf("got %d reports, want 6", len(reports))
+	}
+	if got := reports[0].Params; got["x"] != 0 || got["y"] != 0 {
+		t.Errorf("first combination = %v", got)
+	}
+	// In the corner two arms of the cross are always out of bounds.
+	for _, m := range reports[0].Metrics {
+		if m.Name == MetricOutOfBounds && (m.Min != 2 || m.Max != 2) {
+			t.Errorf("corner out of bounds = %+v", m)
+		}
+	}
+}
+
+func TestRun_ShotsAndFailures(t *testing.T) {
+	shot := items.Item{ID: 1, Name: "shot", Script: `[{"MAKE_SHOT": {"x": "x", "y": "y"}}]`}
+	reports, err := Run(Config{Item: shot, Catalogue: []items.Item{shot}, Runs: 500, Seed: 3, Params: map[string]Range{"x": {0, 10}, "y": {0, 9}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := reports[0]
+	if r.Failed == 0 || r.Shots+r.Failed != 500 {
+		t.Errorf("failed=%d shots=%d", r.Failed, r.Shots)
+	}
+	// 20 of 100 cells hold a ship.
+	if r.HitProbability < 0.1 || r.HitProbability > 0.3 {
+		t.Errorf("hit probability = %v", r.HitProbability)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	got, err := ParseParams("x=0..9, y=4,direction=1..2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]Range{"x": {0, 9}, "y": {4, 4}, "direction": {1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, bad := range []string{"x", "x=a", "x=1..b", "=1"} {
+		if _, err := ParseParams(bad); err == nil {
+			t.Errorf("expected %q to fail", bad)
+		}
+	}
+	if _, err := Run(Config{Runs: 1, Params: map[string]Range{"x": {3, 1}}}); err == nil {
+		t.Error("expected an empty range to fail")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	reports := []Report{{Item: "a", Runs: 1, Metrics: []Metric{{Name: MetricHits, Mean: 0.5}}}}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, reports); err != nil {
+		t.Fatal(err)
+	}
+	want := "item,params,runs,failed,shots,hit_probability,metric,mean,stddev,min,p50,p90,max\n" +
+		"a,random,1,0,0,0.000,hits,0.500,0.000,0.000,0.000,0.000,0.000\n"
+	if buf.String() != want {
+		t.Errorf("csv:\n%s", buf.String())
+	}
+	buf.Reset()
+	if err := WriteTable(&buf, reports); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 2 || !strings.HasPrefix(lines[1], "a ") {
+		t.Errorf("table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,109 @@
+package balance
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Table is a report laid out for output: one row per report and metric,
+// the report's own columns followed by those of the metric. The match
+// simulator writes its reports through it as well.
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// NewTable returns an empty table with the given report columns followed by
+// the metric columns.
+func NewTable(columns ...string) *Table {
+	return &Table{Columns: append(append([]string(nil), columns...), "metric", "mean", "stddev", "min", "p50", "p90", "max")}
+}
+
+// AddMetrics adds one row per metric, each starting with cells.
+func (t *Table) AddMetrics(cells []string, metrics []Metric) {
+	for _, m := range metrics {
+		row := append(append([]string(nil), cells...),
+			m.Name,
+			FormatFloat(m.Mean),
+			FormatFloat(m.StdDev),
+			FormatFloat(m.Min),
+			FormatFloat(m.P50),
+			FormatFloat(m.P90),
+			FormatFloat(m.Max),
+		)
+		t.Rows = append(t.Rows, row)
+	}
+}
+
+// WriteCSV writes the rows with a header.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Columns); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// WriteText writes the rows of WriteCSV as an aligned text table.
+func (t *Table) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.Columns, "\t")+"\t")
+	for _, row := range t.Rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+	return tw.Flush()
+}
+
+func table(reports []Report) *Table {
+	t := NewTable("item", "params", "runs", "failed", "shots", "hit_probability")
+	for _, r := range reports {
+		t.AddMetrics([]string{
+			r.Item,
+			formatParams(r.Params),
+			strconv.Itoa(r.Runs),
+			strconv.Itoa(r.Failed),
+			strconv.Itoa(r.Shots),
+			FormatFloat(r.HitProbability),
+		}, r.Metrics)
+	}
+	return t
+}
+
+// WriteCSV writes one row per report and metric, with a header.
+func WriteCSV(w io.Writer, reports []Report) error {
+	return table(reports).WriteCSV(w)
+}
+
+// WriteTable writes the rows of WriteCSV as an aligned text table.
+func WriteTable(w io.Writer, reports []Report) error {
+	return table(reports).WriteText(w)
+}
+
+func formatParams(params map[string]int) string {
+	if params == nil {
+		return "random"
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, params[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// FormatFloat formats a report value with three decimals.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 3, 64)
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"sort"
+)
+
+const size = 10
+
+// Mark is what a bot knows about a cell of the enemy board.
+type Mark uint8
+
+const (
+	Unknown Mark = iota
+	Miss
+	Hit
+	Sunk
+)
+
+// Board is the enemy board as a bot sees it: the outcome of its own shots and
+// the ships still afloat.
+type Board struct {
+	Cells [size][size]Mark
+	// Remaining holds the sizes of the ships not yet sunk, largest first.
+	Remaining []int
+}
+
+func NewBoard() *Board {
+	b := &Board{}
+	for _, def := range game.AllowedShips {
+		for i := 0; i < def.Count; i++ {
+			b.Remaining = append(b.Remaining, def.Size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(b.Remaining)))
+	return b
+}
+
+func (b *Board) At(c game.Coord) Mark {
+	if !inside(c) {
+		return Miss
+	}
+	return b.Cells[c.X][c.Y]
+}
+
+// Record notes the outcome of a shot. A sunk ship is marked as a whole, and
+// the cells next to it as misses, since ships never touch by a side.
+func (b *Board) Record(c game.Coord, hit, sunk bool) {
+	if !inside(c) {
+		return
+	}
+	if !hit {
+		b.Cells[c.X][c.Y] = Miss
+		return
+	}
+	b.Cells[c.X][c.Y] = Hit
+	if !sunk {
+		return
+	}
+	ship := b.connectedHits(c)
+	for _, cell := range ship {
+		b.Cells[cell.X][cell.Y] = Sunk
+	}
+	for _, cell := range ship {
+		for _, n := range neighbours(cell) {
+			if b.At(n) == Unknown {
+				b.Cells[n.X][n.Y] = Miss
+			}
+		}
+	}
+	for i, s := range b.Remaining {
+		if s == len(ship) {
+			b.Remaining = append(b.Remaining[:i], b.Remaining[i+1:]...)
+			break
+		}
+	}
+}
+
+// connectedHits returns the hit cells side-connected to c.
+func (b *Board) connectedHits(c game.Coord) []game.Coord {
+	seen := map[game.Coord]bool{c: true}
+	cells := []game.Coord{c}
+	for i := 0; i < len(cells); i++ {
+		for _, n := range neighbours(cells[i]) {
+			if !seen[n] && b.At(n) == Hit {
+				seen[n] = true
+				cells = append(cells, n)
+			}
+		}
+	}
+	return cells
+}
+
+// Hits lists the cells hit on ships not yet sunk.
+func (b *Board) Hits() []game.Coord {
+	return b.cells(Hit)
+}
+
+func (b *Board) Unknown() []game.Coord {
+	return b.cells(Unknown)
+}
+
+func (b *Board) cells(m Mark) []game.Coord {
+	var cells []game.Coord
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if b.Cells[x][y] == m {
+				cells = append(cells, game.Coord{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
+
+func inside(c game.Coord) bool {
+	return c.X >= 0 && c.Y >= 0 && c.X < size && c.Y < size
+}
+
+func neighbours(c game.Coord) []game.Coord {
+	return []game.Coord{{X: c.X - 1, Y: c.Y}, {X: c.X + 1, Y: c.Y}, {X: c.X, Y: c.Y - 1}, {X: c.X, Y: c.Y + 1}}
+}
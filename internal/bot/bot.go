@@ -0,0 +1,76 @@
+// Package bot implements computer opponents that play a room in place of a
+// websocket client.
+package bot
+
+import (
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/match"
+	"sort"
+)
+
+// Difficulty levels, as selected in the start-match payload.
+const (
+	LevelEasy   = "easy"
+	LevelMedium = "medium"
+	LevelHard   = "hard"
+)
+
+var levels = map[string]func() Strategy{
+	LevelEasy:   func() Strategy { return Random{} },
+	LevelMedium: func() Strategy { return HuntTarget{} },
+	LevelHard:   func() Strategy { return Density{} },
+}
+
+// Bot is a match.Agent that shoots with a strategy. It only learns what a
+// human would: whether its shots hit and which ships sank.
+type Bot struct {
+	Strategy Strategy
+	Board    *Board
+
+	rng game.Intn
+}
+
+func New(level string, r game.Intn) (*Bot, error) {
+	strategy, ok := levels[level]
+	if !ok {
+		return nil, fmt.Errorf("unknown bot level %q", level)
+	}
+	return NewWithStrategy(strategy(), r), nil
+}
+
+func NewWithStrategy(s Strategy, r game.Intn) *Bot {
+	return &Bot{Strategy: s, Board: NewBoard(), rng: r}
+}
+
+// Fleet places the whole fleet at random.
+func (b *Bot) Fleet() ([]game.Ship, error) {
+	gs, err := game.RandomFleet(b.rng)
+	if err != nil {
+		return nil, err
+	}
+	ships := make([]game.Ship, 0, len(gs.Ships))
+	for _, ship := range gs.Ships {
+		ship.ID = ""
+		ships = append(ships, ship)
+	}
+	sort.Slice(ships, func(i, j int) bool {
+		a, b := ships[i], ships[j]
+		if len(a.Coords) != len(b.Coords) {
+			return len(a.Coords) > len(b.Coords)
+		}
+		if a.Coords[0].X != b.Coords[0].X {
+			return a.Coords[0].X < b.Coords[0].X
+		}
+		return a.Coords[0].Y < b.Coords[0].Y
+	})
+	return ships, nil
+}
+
+func (b *Bot) NextShot() game.Coord {
+	return b.Strategy.Next(b.Board, b.rng)
+}
+
+func (b *Bot) Observe(shot *match.Shot) {
+	b.Board.Record(shot.Target, shot.Hit, shot.Sunk)
+}
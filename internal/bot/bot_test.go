@@ -0,0 +1,180 @@
+package bot
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/match"
+	"lesta-battleship/server-core/internal/rng"
+	"testing"
+)
+
+// play lets a strategy shoot at a random fleet until it sinks it and returns
+// the number of shots.
+func play(t *testing.T, s Strategy, seed int64) int {
+	t.Helper()
+	fleet, err := game.RandomFleet(rng.New(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewWithStrategy(s, rng.New(seed+1))
+	for shots := 1; shots <= 100; shots++ {
+		c := b.NextShot()
+		if b.Board.At(c) != Unknown {
+			t.Fatalf("shot %d at %v, which is already known", shots, c)
+		}
+		cmd := &game.ShootCommand{Target: c}
+		if err := cmd.Apply(fleet); err != nil {
+			t.Fatal(err)
+		}
+		b.Observe(&match.Shot{Target: c, Hit: cmd.Hit, Sunk: cmd.Hit && fleet.ShipSunk(c)})
+		if fleet.ShipCellsLeft() == 0 {
+			if len(b.Board.Remaining) != 0 {
+				t.Errorf("fleet sunk, bot still expects %v", b.Board.Remaining)
+			}
+			return shots
+		}
+	}
+	t.Fatal("fleet not sunk in 100 shots")
+	return 0
+}
+
+func TestStrategies(t *testing.T) {
+	const games = 20
+	avg := map[string]float64{}
+	for level, strategy := range levels {
+		total := 0
+		for seed := int64(1); seed <= games; seed++ {
+			total += play(t, strategy(), seed)
+		}
+		avg[level] = float64(total) / games
+	}
+	t.Logf("average shots to win: %v", avg)
+	if !(avg[LevelHard] < avg[LevelMedium] && avg[LevelMedium] < avg[LevelEasy]) {
+		t.Errorf("levels are not ordered by strength: %v", avg)
+	}
+}
+
+func TestBoard_RecordSunk(t *testing.T) {
+	b := NewBoard()
+	b.Record(game.Coord{X: 4, Y: 4}, true, false)
+	b.Record(game.Coord{X: 5, Y: 4}, true, true)
+	if b.At(game.Coord{X: 4, Y: 4}) != Sunk || b.At(game.Coord{X: 5, Y: 4}) != Sunk {
+		t.Error("ship cells not marked sunk")
+	}
+	for _, c := range []game.Coord{{X: 3, Y: 4}, {X: 6, Y: 4}, {X: 4, Y: 3}, {X: 5, Y: 5}} {
+		if b.At(c) != Miss {
+			t.Errorf("%v next to the sunk ship is %v, want a miss", c, b.At(c))
+		}
+	}
+	if b.At(game.Coord{X: 3, Y: 3}) != Unknown {
+		t.Error("diagonal cell marked, but ships may touch by a corner")
+	}
+	if len(b.Remaining) != 9 {
+		t.Errorf("remaining = %v", b.Remaining)
+	}
+}
+
+func TestHuntTarget_FollowsAxis(t *testing.T) {
+	b := NewBoard()
+	b.Record(game.Coord{X: 4, Y: 4}, true, false)
+	b.Record(game.Coord{X: 4, Y: 5}, true, false)
+	r := rng.New(1)
+	for i := 0; i < 10; i++ {
+		c := HuntTarget{}.Next(b, r)
+		if c != (game.Coord{X: 4, Y: 3}) && c != (game.Coord{X: 4, Y: 6}) {
+			t.Fatalf("shot at %v, off the axis of the hits", c)
+		}
+	}
+}
+
+func TestAgent_PlaysRoom(t *testing.T) {
+	room := match.NewGameRoom(match.Options{RoomID: "r1", Player1: "p1", Player2: "bot", Seed: 3})
+	b, err := New(LevelHard, room.RNG.Fork())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := room.AddAgent("bot", b); err != nil {
+		t.Fatal(err)
+	}
+	if !room.Player2.Ready || room.Player2.State.ShipCellsLeft() != 20 {
+		t.Fatalf("bot fleet not placed: ready=%v cells=%d", room.Player2.Ready, room.Player2.State.ShipCellsLeft())
+	}
+
+	fleet, _ := game.RandomFleet(rng.New(4))
+	room.Player1.State = fleet
+	room.Status, room.Turn = "playing", "bot"
+
+	var shots []*match.Shot
+	if err := room.PlayAgents(func(s *match.Shot) { shots = append(shots, s) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(shots) != 1 || room.Turn != "p1" || shots[0].NextTurn != "p1" {
+		t.Errorf("bot took %d shots, turn is %s", len(shots), room.Turn)
+	}
+
+	if _, err := New("impossible", rng.New(1)); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+}
+
+func TestAgent_FinishesShieldedFleet(t *testing.T) {
+	for seed := int64(1); seed <= 10; seed++ {
+		room := match.NewGameRoom(match.Options{RoomID: "r1", Player1: "p1", Player2: "bot", Seed: seed})
+		b, err := New(LevelMedium, room.RNG.Fork())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := room.AddAgent("bot", b); err != nil {
+			t.Fatal(err)
+		}
+		fleet, _ := game.RandomFleet(rng.New(seed))
+		// A shield on every ship: the shots it absorbs read as final misses.
+		for _, ship := range fleet.Ships {
+			shield := &game.AddEffectCommand{Effect: game.StatusEffect{Kind: game.EffectShield, Cell: ship.Coords[0], TurnsLeft: 1000}}
+			if err := shield.Apply(fleet); err != nil {
+				t.Fatal(err)
+			}
+		}
+		room.Player1.State = fleet
+		room.OnRejectedMove = func(playerID string, err error) {
+			t.Errorf("seed %d: bot move rejected: %v", seed, err)
+		}
+		room.Start("bot")
+
+		for turn := 0; room.Status == "playing"; turn++ {
+			if turn > 200 {
+				t.Fatalf("seed %d: no winner after %d turns", seed, turn)
+			}
+			if err := room.PlayAgents(nil); err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			if room.Turn == "p1" {
+				room.PassTurn("bot")
+			}
+		}
+		if room.WinnerID != "bot" {
+			t.Errorf("seed %d: winner %q", seed, room.WinnerID)
+		}
+	}
+}
+
+// offBoard always fires off the board.
+type offBoard struct{}
+
+func (offBoard) Next(b *Board, r game.Intn) game.Coord { return game.Coord{X: -1, Y: -1} }
+
+func TestAgent_RejectedShotFallsBack(t *testing.T) {
+	room := match.NewGameRoom(match.Options{RoomID: "r1", Player1: "p1", Player2: "bot", Seed: 1})
+	if err := room.AddAgent("bot", NewWithStrategy(offBoard{}, rng.New(1))); err != nil {
+		t.Fatal(err)
+	}
+	room.Player1.State, _ = game.RandomFleet(rng.New(2))
+	room.Start("bot")
+
+	var shots []*match.Shot
+	if err := room.PlayAgents(func(s *match.Shot) { shots = append(shots, s) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(shots) == 0 || shots[0].Target != (game.Coord{X: 0, Y: 0}) {
+		t.Errorf("shots = %+v", shots)
+	}
+}
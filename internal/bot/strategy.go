@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"lesta-battleship/server-core/internal/game"
+)
+
+// Strategy chooses where a bot fires next. It must return a cell the bot has
+// not shot at yet while there is one.
+type Strategy interface {
+	Next(b *Board, r game.Intn) game.Coord
+}
+
+// Random fires at any cell not shot at yet.
+type Random struct{}
+
+func (Random) Next(b *Board, r game.Intn) game.Coord {
+	return pick(b.Unknown(), r)
+}
+
+// HuntTarget hunts on a checkerboard spaced by the smallest ship afloat, and
+// once it hits, follows up along the axis of the hits until the ship sinks.
+type HuntTarget struct{}
+
+func (HuntTarget) Next(b *Board, r game.Intn) game.Coord {
+	if cells := targets(b); len(cells) > 0 {
+		return pick(cells, r)
+	}
+	return hunt(b, r)
+}
+
+// targets returns the cells worth trying next to the first ship hit but not
+// sunk: the ends of the line of hits, or every side of a single hit.
+func targets(b *Board) []game.Coord {
+	seen := map[game.Coord]bool{}
+	for _, hit := range b.Hits() {
+		if seen[hit] {
+			continue
+		}
+		ship := b.connectedHits(hit)
+		for _, c := range ship {
+			seen[c] = true
+		}
+		if len(ship) > 1 {
+			if cells := lineEnds(b, ship); len(cells) > 0 {
+				return cells
+			}
+		}
+		var cells []game.Coord
+		for _, c := range ship {
+			for _, n := range neighbours(c) {
+				if b.At(n) == Unknown {
+					cells = append(cells, n)
+				}
+			}
+		}
+		if len(cells) > 0 {
+			return cells
+		}
+	}
+	return nil
+}
+
+// lineEnds returns the unknown cells extending a straight line of hits.
+func lineEnds(b *Board, line []game.Coord) []game.Coord {
+	lo, hi := line[0], line[0]
+	for _, c := range line {
+		if c.X < lo.X || c.Y < lo.Y {
+			lo = c
+		}
+		if c.X > hi.X || c.Y > hi.Y {
+			hi = c
+		}
+	}
+	var before, after game.Coord
+	switch {
+	case lo.Y == hi.Y:
+		before, after = game.Coord{X: lo.X - 1, Y: lo.Y}, game.Coord{X: hi.X + 1, Y: hi.Y}
+	case lo.X == hi.X:
+		before, after = game.Coord{X: lo.X, Y: lo.Y - 1}, game.Coord{X: hi.X, Y: hi.Y + 1}
+	default:
+		return nil
+	}
+	var cells []game.Coord
+	for _, c := range []game.Coord{before, after} {
+		if b.At(c) == Unknown {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+func hunt(b *Board, r game.Intn) game.Coord {
+	unknown := b.Unknown()
+	if len(b.Remaining) == 0 {
+		return pick(unknown, r)
+	}
+	spacing := b.Remaining[len(b.Remaining)-1]
+	var cells []game.Coord
+	for _, c := range unknown {
+		if (c.X+c.Y)%spacing == 0 {
+			cells = append(cells, c)
+		}
+	}
+	if len(cells) == 0 {
+		return pick(unknown, r)
+	}
+	return pick(cells, r)
+}
+
+// targetWeight is how much more a placement through a hit counts than one
+// through unknown cells only.
+const targetWeight = 50
+
+// Density fires at the cell covered by the most placements of the ships still
+// afloat that fit the misses and sunk ships known so far. Placements through
+// hits weigh more, so it finishes off a ship once it has found it.
+type Density struct{}
+
+func (Density) Next(b *Board, r game.Intn) game.Coord {
+	var score [size][size]int
+	for _, n := range b.Remaining {
+		for x := 0; x < size; x++ {
+			for y := 0; y < size; y++ {
+				for _, horizontal := range []bool{true, false} {
+					addPlacement(b, &score, game.Coord{X: x, Y: y}, n, horizontal)
+				}
+			}
+		}
+	}
+
+	best, bestScore := []game.Coord(nil), 0
+	for _, c := range b.Unknown() {
+		switch s := score[c.X][c.Y]; {
+		case s > bestScore:
+			best, bestScore = []game.Coord{c}, s
+		case s == bestScore && s > 0:
+			best = append(best, c)
+		}
+	}
+	if len(best) == 0 {
+		return pick(b.Unknown(), r)
+	}
+	return pick(best, r)
+}
+
+func addPlacement(b *Board, score *[size][size]int, start game.Coord, n int, horizontal bool) {
+	cells := make([]game.Coord, n)
+	hits := 0
+	for i := range cells {
+		c := game.Coord{X: start.X, Y: start.Y + i}
+		if horizontal {
+			c = game.Coord{X: start.X + i, Y: start.Y}
+		}
+		switch b.At(c) {
+		case Miss, Sunk:
+			return
+		case Hit:
+			hits++
+		}
+		cells[i] = c
+	}
+	weight := 1 + targetWeight*hits
+	for _, c := range cells {
+		if b.At(c) == Unknown {
+			score[c.X][c.Y] += weight
+		}
+	}
+}
+
+// pick draws one of cells; with none left it returns an off-board cell, which
+// the room rejects.
+func pick(cells []game.Coord, r game.Intn) game.Coord {
+	if len(cells) == 0 {
+		return game.Coord{X: -1, Y: -1}
+	}
+	return cells[r.Intn(len(cells))]
+}
@@ -0,0 +1,188 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Glyphs used by the text form of a board, one per cell state.
+var cellGlyphs = map[CellState]byte{
+	Empty:    '~',
+	ShipCell: 'S',
+	Miss:     'o',
+	Hit:      'X',
+	Revealed: '.',
+	Shielded: '*',
+}
+
+func (c CellState) String() string {
+	switch c {
+	case Empty:
+		return "empty"
+	case ShipCell:
+		return "ship"
+	case Miss:
+		return "miss"
+	case Hit:
+		return "hit"
+	case Revealed:
+		return "revealed"
+	case Shielded:
+		return "shielded"
+	}
+	return fmt.Sprintf("CellState(%d)", int(c))
+}
+
+// FormatBoard renders the field as text, one row per line, with column and
+// row numbers:
+//
+//	   0 1 2 3 4 5 6 7 8 9
+//	0  ~ ~ S ~ ~ ~ ~ ~ ~ ~
+func FormatBoard(gs *GameState) string {
+	var b strings.Builder
+	b.WriteString("  ")
+	for x := 0; x < len(gs.Field); x++ {
+		fmt.Fprintf(&b, " %d", x)
+	}
+	b.WriteByte('\n')
+	for y := 0; y < len(gs.Field[0]); y++ {
+		fmt.Fprintf(&b, "%d ", y)
+		for x := 0; x < len(gs.Field); x++ {
+			b.WriteByte(' ')
+			b.WriteByte(cellGlyphs[gs.Field[x][y]])
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ParseBoard reads a board in the glyphs of FormatBoard: ten rows of ten
+// cells, spaces optional. Blank lines and lines starting with # are skipped,
+// and so are the header and row numbers FormatBoard writes. Ships are built
+// from straight runs of S, X and * cells; fleet counts are not enforced.
+func ParseBoard(text string) (*GameState, error) {
+	gs := NewGameState()
+	y := 0
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "0 1") {
+			continue
+		}
+		row := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' {
+				return -1
+			}
+			return r
+		}, line)
+		if len(row) == len(gs.Field)+1 && row[0] >= '0' && row[0] <= '9' {
+			row = row[1:]
+		}
+		if len(row) != len(gs.Field) {
+			return nil, fmt.Errorf("line %d: want %d cells, got %q", n+1, len(gs.Field), line)
+		}
+		if y >= len(gs.Field[0]) {
+			return nil, fmt.Errorf("line %d: too many rows", n+1)
+		}
+		for x := 0; x < len(row); x++ {
+			state, ok := glyphState(row[x])
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown cell %q", n+1, row[x])
+			}
+			gs.Field[x][y] = state
+		}
+		y++
+	}
+	if y != len(gs.Field[0]) {
+		return nil, fmt.Errorf("want %d rows, got %d", len(gs.Field[0]), y)
+	}
+	if err := gs.buildShips(); err != nil {
+		return nil, err
+	}
+	return gs, nil
+}
+
+func glyphState(g byte) (CellState, bool) {
+	for state, glyph := range cellGlyphs {
+		if glyph == g {
+			return state, true
+		}
+	}
+	return Empty, false
+}
+
+// buildShips groups the ship and hit cells of the field into ships.
+func (gs *GameState) buildShips() error {
+	seen := map[Coord]bool{}
+	isShip := func(c Coord) bool {
+		if !gs.isInside(c) {
+			return false
+		}
+		state := gs.Field[c.X][c.Y]
+		return state == ShipCell || state == Hit || state == Shielded
+	}
+	for x := 0; x < len(gs.Field); x++ {
+		for y := 0; y < len(gs.Field[x]); y++ {
+			start := Coord{X: x, Y: y}
+			if seen[start] || !isShip(start) {
+				continue
+			}
+			var coords []Coord
+			stack := []Coord{start}
+			seen[start] = true
+			for len(stack) > 0 {
+				c := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				coords = append(coords, c)
+				for _, d := range []Coord{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}} {
+					next := Coord{X: c.X + d.X, Y: c.Y + d.Y}
+					if !seen[next] && isShip(next) {
+						seen[next] = true
+						stack = append(stack, next)
+					}
+				}
+			}
+			sort.Slice(coords, func(i, j int) bool {
+				if coords[i].X != coords[j].X {
+					return coords[i].X < coords[j].X
+				}
+				return coords[i].Y < coords[j].Y
+			})
+
+			ship := Ship{Coords: coords}
+			for t, def := range AllowedShips {
+				if def.Size == len(coords) {
+					ship.Type = t
+				}
+			}
+			if ship.Type == "" || !gs.isValidShip(ship) {
+				return fmt.Errorf("cells at (%d,%d) do not form a valid ship", x, y)
+			}
+			gs.shipIDSeq++
+			ship.ID = fmt.Sprintf("%d", gs.shipIDSeq)
+			gs.Ships[ship.ID] = ship
+		}
+	}
+	return nil
+}
+
+// CellChange is a cell whose state differs between two boards.
+type CellChange struct {
+	Cell Coord     `json:"cell"`
+	From CellState `json:"from"`
+	To   CellState `json:"to"`
+}
+
+// DiffFields lists the cells that differ between two fields, column by
+// column.
+func DiffFields(before, after [10][10]CellState) []CellChange {
+	var changes []CellChange
+	for x := range before {
+		for y := range before[x] {
+			if before[x][y] != after[x][y] {
+				changes = append(changes, CellChange{Cell: Coord{X: x, Y: y}, From: before[x][y], To: after[x][y]})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,125 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+type EffectKind string
+
+const (
+	// EffectShield makes the next shot on the covered ship count as a miss,
+	// leaving the cell Shielded.
+	EffectShield EffectKind = "shield"
+	// EffectDecoy makes an empty cell report a hit to the next shot.
+	EffectDecoy EffectKind = "decoy"
+	// EffectSonar reports how many ship cells are left in row Cell.Y to
+	// every shot or scouting in that row.
+	EffectSonar EffectKind = "sonar"
+)
+
+// StatusEffect is an effect that stays on a board for a number of turns.
+// TurnsLeft counts down at the start of each turn of the board owner.
+type StatusEffect struct {
+	ID        int        `json:"id"`
+	Kind      EffectKind `json:"kind"`
+	Cell      Coord      `json:"cell"`
+	ShipID    string     `json:"ship_id,omitempty"`
+	TurnsLeft int        `json:"turns_left"`
+}
+
+func (gs *GameState) effectIndex(match func(e StatusEffect) bool) int {
+	for i, e := range gs.Effects {
+		if match(e) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (gs *GameState) removeEffect(i int) StatusEffect {
+	e := gs.Effects[i]
+	gs.Effects = append(gs.Effects[:i:i], gs.Effects[i+1:]...)
+	return e
+}
+
+func (gs *GameState) insertEffect(i int, e StatusEffect) {
+	gs.Effects = append(gs.Effects[:i:i], append([]StatusEffect{e}, gs.Effects[i:]...)...)
+}
+
+func (gs *GameState) shieldIndex(c Coord) int {
+	ship, ok := gs.ShipAt(c)
+	if !ok {
+		return -1
+	}
+	return gs.effectIndex(func(e StatusEffect) bool { return e.Kind == EffectShield && e.ShipID == ship.ID })
+}
+
+func (gs *GameState) decoyIndex(c Coord) int {
+	return gs.effectIndex(func(e StatusEffect) bool { return e.Kind == EffectDecoy && e.Cell == c })
+}
+
+// SonarReading returns the number of ship cells not yet hit in row y, if a
+// sonar covers that row.
+func (gs *GameState) SonarReading(y int) (int, bool) {
+	if gs.effectIndex(func(e StatusEffect) bool { return e.Kind == EffectSonar && e.Cell.Y == y }) < 0 {
+		return 0, false
+	}
+	return gs.ShipCellsInRow(y), true
+}
+
+func (gs *GameState) ShipCellsInRow(y int) int {
+	return gs.CountShipCells(Coord{X: 0, Y: y}, Coord{X: 9, Y: y})
+}
+
+// TickEffects counts down all effects on the board and drops the expired
+// ones. It is called when the board owner's turn starts.
+func (gs *GameState) TickEffects() {
+	kept := gs.Effects[:0]
+	for _, e := range gs.Effects {
+		e.TurnsLeft--
+		if e.TurnsLeft > 0 {
+			kept = append(kept, e)
+		}
+	}
+	gs.Effects = kept
+}
+
+type AddEffectCommand struct {
+	Effect StatusEffect
+}
+
+func (c *AddEffectCommand) Apply(gs *GameState) error {
+	if c.Effect.TurnsLeft <= 0 {
+		return errors.New("effect duration must be positive")
+	}
+	if !gs.isInside(c.Effect.Cell) {
+		return errors.New("effect is out of bounds")
+	}
+	switch c.Effect.Kind {
+	case EffectShield:
+		ship, ok := gs.ShipAt(c.Effect.Cell)
+		if !ok {
+			return fmt.Errorf("no ship at (%d,%d) to shield", c.Effect.Cell.X, c.Effect.Cell.Y)
+		}
+		c.Effect.ShipID = ship.ID
+	case EffectDecoy:
+		if !gs.isCellEmpty(c.Effect.Cell) {
+			return errors.New("decoy needs an empty cell")
+		}
+	case EffectSonar:
+	default:
+		return fmt.Errorf("unknown effect: %s", c.Effect.Kind)
+	}
+	gs.effectIDSeq++
+	c.Effect.ID = gs.effectIDSeq
+	gs.Effects = append(gs.Effects, c.Effect)
+	return nil
+}
+
+func (c *AddEffectCommand) Undo(gs *GameState) {
+	if i := gs.effectIndex(func(e StatusEffect) bool { return e.ID == c.Effect.ID }); i >= 0 {
+		gs.removeEffect(i)
+	}
+	gs.effectIDSeq--
+}
@@ -0,0 +1,66 @@
+package game
+
+import (
+	"errors"
+	"sort"
+)
+
+// Intn is the random source RandomFleet draws from, e.g. *rand.Rand.
+type Intn interface {
+	Intn(n int) int
+}
+
+// fleetAttempts bounds how often RandomFleet starts over when a ship does not
+// fit anywhere it tried.
+const fleetAttempts = 100
+
+// RandomFleet returns a board with the full fleet of AllowedShips placed at
+// random, largest ships first, through PlaceShipCommand so every placement
+// obeys the game rules.
+func RandomFleet(r Intn) (*GameState, error) {
+	types := make([]ShipType, 0, len(AllowedShips))
+	for t := range AllowedShips {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return AllowedShips[types[i]].Size > AllowedShips[types[j]].Size })
+
+	for attempt := 0; attempt < fleetAttempts; attempt++ {
+		if gs, ok := tryFleet(r, types); ok {
+			return gs, nil
+		}
+	}
+	return nil, errors.New("could not place the fleet")
+}
+
+func tryFleet(r Intn, types []ShipType) (*GameState, bool) {
+	gs := NewGameState()
+	for _, t := range types {
+		def := AllowedShips[t]
+		for n := 0; n < def.Count; n++ {
+			if !placeRandom(gs, r, t, def.Size) {
+				return nil, false
+			}
+		}
+	}
+	return gs, true
+}
+
+func placeRandom(gs *GameState, r Intn, t ShipType, size int) bool {
+	for try := 0; try < 200; try++ {
+		start := Coord{X: r.Intn(10), Y: r.Intn(10)}
+		horizontal := r.Intn(2) == 0
+		coords := make([]Coord, size)
+		for i := range coords {
+			if horizontal {
+				coords[i] = Coord{X: start.X + i, Y: start.Y}
+			} else {
+				coords[i] = Coord{X: start.X, Y: start.Y + i}
+			}
+		}
+		cmd := &PlaceShipCommand{Ship: Ship{Type: t, Coords: coords}}
+		if cmd.Apply(gs) == nil {
+			return true
+		}
+	}
+	return false
+}
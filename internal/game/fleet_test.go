@@ -0,0 +1,28 @@
+package game
+
+import (
+	"lesta-battleship/server-core/internal/rng"
+	"testing"
+)
+
+func TestRandomFleet_IsLegal(t *testing.T) {
+	source := rng.New(42)
+	for i := 0; i < 50; i++ {
+		fleet, err := RandomFleet(source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Replaying the ships onto an empty board checks sizes, counts,
+		// bounds and spacing with the game's own rules.
+		replay := NewGameState()
+		for _, ship := range fleet.Ships {
+			ship.ID = ""
+			if err := (&PlaceShipCommand{Ship: ship}).Apply(replay); err != nil {
+				t.Fatalf("fleet %d: %v", i, err)
+			}
+		}
+		if len(replay.Ships) != 10 || replay.ShipCellsLeft() != 20 {
+			t.Fatalf("fleet %d has %d ships and %d cells", i, len(replay.Ships), replay.ShipCellsLeft())
+		}
+	}
+}
@@ -8,6 +8,10 @@ const (
 	Miss
 	Hit
 	Revealed
+	// Shielded is a ship cell whose shot a shield absorbed. The shooter was
+	// told it missed, so like a miss it cannot be shot again, and it no
+	// longer counts as afloat.
+	Shielded
 )
 
 type Coord struct {
@@ -44,7 +48,10 @@ type GameState struct {
 	Field     [10][10]CellState
 	Ships     map[string]Ship
 	ShotsMade []Coord
-	shipIDSeq int // <-- Add this line
+	Effects   []StatusEffect
+
+	shipIDSeq   int
+	effectIDSeq int
 }
 
 func NewGameState() *GameState {
@@ -53,6 +60,25 @@ func NewGameState() *GameState {
 	}
 }
 
+// Clone returns a deep copy of the state, so it can be mutated freely
+// without affecting the original.
+func (gs *GameState) Clone() *GameState {
+	c := &GameState{
+		Field:     gs.Field,
+		Ships:     make(map[string]Ship, len(gs.Ships)),
+		ShotsMade: append([]Coord(nil), gs.ShotsMade...),
+		Effects:   append([]StatusEffect(nil), gs.Effects...),
+
+		shipIDSeq:   gs.shipIDSeq,
+		effectIDSeq: gs.effectIDSeq,
+	}
+	for id, ship := range gs.Ships {
+		ship.Coords = append([]Coord(nil), ship.Coords...)
+		c.Ships[id] = ship
+	}
+	return c
+}
+
 func (gs *GameState) isInside(c Coord) bool {
 	return c.X >= 0 && c.X < 10 && c.Y >= 0 && c.Y < 10
 }
@@ -113,11 +139,14 @@ func OpenCell(x, y int, gs *GameState) string {
 	}
 	switch gs.Field[x][y] {
 	case Empty:
+		if gs.decoyIndex(Coord{X: x, Y: y}) >= 0 {
+			return "ship"
+		}
 		gs.Field[x][y] = Revealed
 		return "empty"
 	case ShipCell:
 		return "ship"
-	case Miss:
+	case Miss, Shielded:
 		return "miss"
 	case Hit:
 		return "hit"
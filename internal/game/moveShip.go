@@ -0,0 +1,61 @@
+package game
+
+import "fmt"
+
+// MoveShipCommand moves the ship occupying From so that it starts at To,
+// keeping its length. The ship is laid out vertically when To shares From's
+// X, horizontally when it shares From's Y.
+type MoveShipCommand struct {
+	From Coord
+	To   Coord
+
+	Backup Ship
+	prev   map[Coord]CellState
+}
+
+func (c *MoveShipCommand) Apply(gs *GameState) error {
+	ship, ok := gs.ShipAt(c.From)
+	if !ok {
+		return fmt.Errorf("ship not found at (%d,%d)", c.From.X, c.From.Y)
+	}
+
+	newCoords := make([]Coord, len(ship.Coords))
+	for i := range newCoords {
+		if c.To.X == c.From.X {
+			newCoords[i] = Coord{X: c.To.X, Y: c.To.Y + i}
+		} else if c.To.Y == c.From.Y {
+			newCoords[i] = Coord{X: c.To.X + i, Y: c.To.Y}
+		} else {
+			return fmt.Errorf("invalid ship orientation")
+		}
+	}
+	for _, coord := range newCoords {
+		if !gs.isInside(coord) {
+			return fmt.Errorf("new ship position out of bounds")
+		}
+	}
+
+	c.Backup = ship
+	c.prev = make(map[Coord]CellState)
+	for _, coord := range append(append([]Coord(nil), ship.Coords...), newCoords...) {
+		if _, seen := c.prev[coord]; !seen {
+			c.prev[coord] = gs.Field[coord.X][coord.Y]
+		}
+	}
+	for _, coord := range ship.Coords {
+		gs.Field[coord.X][coord.Y] = Empty
+	}
+	for _, coord := range newCoords {
+		gs.Field[coord.X][coord.Y] = ShipCell
+	}
+	ship.Coords = newCoords
+	gs.Ships[ship.ID] = ship
+	return nil
+}
+
+func (c *MoveShipCommand) Undo(gs *GameState) {
+	for coord, state := range c.prev {
+		gs.Field[coord.X][coord.Y] = state
+	}
+	gs.Ships[c.Backup.ID] = c.Backup
+}
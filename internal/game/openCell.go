@@ -0,0 +1,24 @@
+package game
+
+// OpenCellCommand reveals a cell to the player, like OpenCell. Cells outside
+// the board are reported as "invalid" rather than failing, so scouting items
+// may target the edge of the board.
+type OpenCellCommand struct {
+	Target Coord
+	Prev   CellState
+	Result string
+}
+
+func (c *OpenCellCommand) Apply(gs *GameState) error {
+	if gs.isInside(c.Target) {
+		c.Prev = gs.Field[c.Target.X][c.Target.Y]
+	}
+	c.Result = OpenCell(c.Target.X, c.Target.Y, gs)
+	return nil
+}
+
+func (c *OpenCellCommand) Undo(gs *GameState) {
+	if gs.isInside(c.Target) {
+		gs.Field[c.Target.X][c.Target.Y] = c.Prev
+	}
+}
@@ -0,0 +1,26 @@
+package game
+
+import "errors"
+
+// RepairCellCommand turns a hit cell of a ship back into an intact one.
+type RepairCellCommand struct {
+	Target Coord
+}
+
+func (c *RepairCellCommand) Apply(gs *GameState) error {
+	if !gs.isInside(c.Target) {
+		return errors.New("cell out of bounds")
+	}
+	if gs.Field[c.Target.X][c.Target.Y] != Hit {
+		return errors.New("cell is not hit")
+	}
+	if _, ok := gs.ShipAt(c.Target); !ok {
+		return errors.New("no ship to repair")
+	}
+	gs.Field[c.Target.X][c.Target.Y] = ShipCell
+	return nil
+}
+
+func (c *RepairCellCommand) Undo(gs *GameState) {
+	gs.Field[c.Target.X][c.Target.Y] = Hit
+}
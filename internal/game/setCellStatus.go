@@ -0,0 +1,22 @@
+package game
+
+import "errors"
+
+type SetCellStatusCommand struct {
+	Target Coord
+	Status CellState
+	Prev   CellState
+}
+
+func (c *SetCellStatusCommand) Apply(gs *GameState) error {
+	if !gs.isInside(c.Target) {
+		return errors.New("cell out of bounds")
+	}
+	c.Prev = gs.Field[c.Target.X][c.Target.Y]
+	gs.Field[c.Target.X][c.Target.Y] = c.Status
+	return nil
+}
+
+func (c *SetCellStatusCommand) Undo(gs *GameState) {
+	gs.Field[c.Target.X][c.Target.Y] = c.Prev
+}
@@ -4,9 +4,15 @@ import (
 	"errors"
 )
 
+// ShootCommand fires at Target. Hit reports what the shooter is told, which
+// may differ from the board when a shield or decoy is in play.
 type ShootCommand struct {
 	Target Coord
 	Prev   CellState
+	Hit    bool
+
+	consumed      *StatusEffect
+	consumedIndex int
 }
 
 func (c *ShootCommand) Apply(gs *GameState) error {
@@ -14,11 +20,25 @@ func (c *ShootCommand) Apply(gs *GameState) error {
 		return errors.New("out of bounds")
 	}
 	c.Prev = gs.Field[c.Target.X][c.Target.Y]
+	c.consumed = nil
 
 	switch c.Prev {
 	case ShipCell:
+		if i := gs.shieldIndex(c.Target); i >= 0 {
+			c.consume(gs, i)
+			gs.Field[c.Target.X][c.Target.Y] = Shielded
+			c.Hit = false
+			break
+		}
 		gs.Field[c.Target.X][c.Target.Y] = Hit
+		c.Hit = true
 	case Empty:
+		if i := gs.decoyIndex(c.Target); i >= 0 {
+			c.consume(gs, i)
+			c.Hit = true
+		} else {
+			c.Hit = false
+		}
 		gs.Field[c.Target.X][c.Target.Y] = Miss
 	default:
 		return errors.New("already shot here")
@@ -27,7 +47,17 @@ func (c *ShootCommand) Apply(gs *GameState) error {
 	return nil
 }
 
+func (c *ShootCommand) consume(gs *GameState, i int) {
+	e := gs.removeEffect(i)
+	c.consumed = &e
+	c.consumedIndex = i
+}
+
 func (c *ShootCommand) Undo(gs *GameState) {
 	gs.Field[c.Target.X][c.Target.Y] = c.Prev
 	gs.ShotsMade = gs.ShotsMade[:len(gs.ShotsMade)-1]
+	if c.consumed != nil {
+		gs.insertEffect(c.consumedIndex, *c.consumed)
+		c.consumed = nil
+	}
 }
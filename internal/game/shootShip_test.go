@@ -0,0 +1,59 @@
+package game
+
+import "testing"
+
+func TestShootCommand_Shield(t *testing.T) {
+	gs, err := ParseBoard(`
+S S ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (&AddEffectCommand{Effect: StatusEffect{Kind: EffectShield, Cell: Coord{X: 0, Y: 0}, TurnsLeft: 1}}).Apply(gs); err != nil {
+		t.Fatal(err)
+	}
+
+	absorbed := &ShootCommand{Target: Coord{X: 1, Y: 0}}
+	if err := absorbed.Apply(gs); err != nil {
+		t.Fatal(err)
+	}
+	if absorbed.Hit || gs.Field[1][0] != Shielded || len(gs.Effects) != 0 {
+		t.Fatalf("hit=%v cell=%v effects=%v", absorbed.Hit, gs.Field[1][0], gs.Effects)
+	}
+	if err := (&ShootCommand{Target: Coord{X: 1, Y: 0}}).Apply(gs); err == nil {
+		t.Error("shielded cell shot again")
+	}
+	if got := OpenCell(1, 0, gs); got != "miss" {
+		t.Errorf("scouting the shielded cell = %q, want miss", got)
+	}
+
+	hit := &ShootCommand{Target: Coord{X: 0, Y: 0}}
+	if err := hit.Apply(gs); err != nil {
+		t.Fatal(err)
+	}
+	if !hit.Hit || !gs.ShipSunk(Coord{X: 0, Y: 0}) || gs.ShipCellsLeft() != 0 {
+		t.Errorf("ship not sunk: %s", FormatBoard(gs))
+	}
+
+	hit.Undo(gs)
+	absorbed.Undo(gs)
+	if gs.Field[1][0] != ShipCell || len(gs.Effects) != 1 {
+		t.Errorf("undo left cell %v, effects %v", gs.Field[1][0], gs.Effects)
+	}
+
+	// The text form keeps shielded cells as part of their ship.
+	gs.Field[1][0] = Shielded
+	parsed, err := ParseBoard(FormatBoard(gs))
+	if err != nil || len(parsed.Ships) != 1 || parsed.Field[1][0] != Shielded {
+		t.Errorf("parsed %v, %v", parsed, err)
+	}
+}
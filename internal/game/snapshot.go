@@ -0,0 +1,41 @@
+package game
+
+// Snapshot is a GameState as recorded in a match journal. Unlike the state
+// itself it survives a JSON round trip whole, including the sequences new
+// ship and effect IDs are drawn from.
+type Snapshot struct {
+	Field       [10][10]CellState `json:"field"`
+	Ships       map[string]Ship   `json:"ships"`
+	ShotsMade   []Coord           `json:"shots_made"`
+	Effects     []StatusEffect    `json:"effects"`
+	ShipIDSeq   int               `json:"ship_id_seq"`
+	EffectIDSeq int               `json:"effect_id_seq"`
+}
+
+func (gs *GameState) Snapshot() Snapshot {
+	c := gs.Clone()
+	return Snapshot{
+		Field:       c.Field,
+		Ships:       c.Ships,
+		ShotsMade:   c.ShotsMade,
+		Effects:     c.Effects,
+		ShipIDSeq:   c.shipIDSeq,
+		EffectIDSeq: c.effectIDSeq,
+	}
+}
+
+// State returns a new GameState as it was when the snapshot was taken.
+func (s Snapshot) State() *GameState {
+	gs := &GameState{
+		Field:       s.Field,
+		Ships:       make(map[string]Ship, len(s.Ships)),
+		ShotsMade:   s.ShotsMade,
+		Effects:     s.Effects,
+		shipIDSeq:   s.ShipIDSeq,
+		effectIDSeq: s.EffectIDSeq,
+	}
+	for id, ship := range s.Ships {
+		gs.Ships[id] = ship
+	}
+	return gs.Clone()
+}
@@ -3,3 +3,115 @@ package game
 func ShipSize(c Ship) int {
 	return len(c.Coords)
 }
+
+func (gs *GameState) ShipAt(c Coord) (Ship, bool) {
+	for _, ship := range gs.Ships {
+		for _, coord := range ship.Coords {
+			if coord == c {
+				return ship, true
+			}
+		}
+	}
+	return Ship{}, false
+}
+
+// ShipSunk reports whether the ship at c has been shot in every cell, hit or
+// shielded.
+func (gs *GameState) ShipSunk(c Coord) bool {
+	ship, ok := gs.ShipAt(c)
+	if !ok {
+		return false
+	}
+	for _, coord := range ship.Coords {
+		if state := gs.Field[coord.X][coord.Y]; state != Hit && state != Shielded {
+			return false
+		}
+	}
+	return true
+}
+
+// ShipsAfloat counts the ships with at least one cell not hit yet.
+func (gs *GameState) ShipsAfloat() int {
+	afloat := 0
+	for _, s := range gs.Ships {
+		for _, coord := range s.Coords {
+			if gs.Field[coord.X][coord.Y] == ShipCell {
+				afloat++
+				break
+			}
+		}
+	}
+	return afloat
+}
+
+// ShipCellsLeft counts the ship cells that have not been hit yet.
+func (gs *GameState) ShipCellsLeft() int {
+	left := 0
+	for _, s := range gs.Ships {
+		for _, coord := range s.Coords {
+			if gs.Field[coord.X][coord.Y] == ShipCell {
+				left++
+			}
+		}
+	}
+	return left
+}
+
+// CountShipCells counts the ship cells not yet hit in the rectangle spanned by
+// a and b, clipped to the field.
+func (gs *GameState) CountShipCells(a, b Coord) int {
+	minX, maxX := clamp(min(a.X, b.X)), clamp(max(a.X, b.X))
+	minY, maxY := clamp(min(a.Y, b.Y)), clamp(max(a.Y, b.Y))
+	n := 0
+	for x := minX; x <= maxX; x++ {
+		for y := minY; y <= maxY; y++ {
+			if gs.Field[x][y] == ShipCell {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// NearestShipDistance returns the Manhattan distance from c to the closest
+// ship cell not yet hit.
+func (gs *GameState) NearestShipDistance(c Coord) (int, bool) {
+	best, found := 0, false
+	for x := range gs.Field {
+		for y := range gs.Field[x] {
+			if gs.Field[x][y] != ShipCell {
+				continue
+			}
+			d := abs(x-c.X) + abs(y-c.Y)
+			if !found || d < best {
+				best, found = d, true
+			}
+		}
+	}
+	return best, found
+}
+
+// EmptyCells lists the cells that hold no ship and were never shot at or
+// revealed, column by column.
+func (gs *GameState) EmptyCells() []Coord {
+	var cells []Coord
+	for x := range gs.Field {
+		for y := range gs.Field[x] {
+			if gs.Field[x][y] == Empty {
+				cells = append(cells, Coord{X: x, Y: y})
+			}
+		}
+	}
+	return cells
+}
+
+func clamp(v int) int {
+	return max(0, min(v, 9))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,255 @@
+package items
+
+import (
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+)
+
+// constructs are the parts of the script language itself. Every other action
+// comes from the registry.
+var constructs = map[string]handler{
+	"SWITCH_CASE": runSwitch,
+	"SWICH_CASE":  runSwitch,
+	"IF":          runIf,
+	"LET":         runLet,
+	"REPEAT":      runRepeat,
+	"FOR":         runFor,
+}
+
+func number(name string) ArgSpec { return ArgSpec{Name: name} }
+
+var (
+	xy         = []ArgSpec{number("x"), number("y")}
+	rect       = []ArgSpec{number("x"), number("y"), number("x2"), number("y2")}
+	cellEffect = []ArgSpec{number("x"), number("y"), number("turns")}
+)
+
+func init() {
+	Register("OPEN_CELL", ActionSpec{Args: xy, Group: GroupCore}, openCell)
+	Register("MAKE_SHOT", ActionSpec{Args: xy, Group: GroupCore}, makeShot)
+	Register("SET_CELL_STATUS", ActionSpec{
+		Args:   []ArgSpec{number("x"), number("y"), {Name: "status", Type: ArgString, Values: []string{"water", "ship", "shipwreck"}, Label: "cell status"}},
+		Target: TargetOwn,
+		Group:  GroupCore,
+	}, setCellStatus)
+	Register("SET_SHIP_COORDINATES", ActionSpec{Args: rect, Target: TargetOwn, Group: GroupCore}, setShipCoordinates)
+	Register("END_PLAYER_ACTION", ActionSpec{Group: GroupCore}, endPlayerAction)
+
+	Register("ADD_SHIELD", ActionSpec{Args: cellEffect, Target: TargetOwn, Group: GroupEffects}, addCellEffect(game.EffectShield))
+	Register("ADD_DECOY", ActionSpec{Args: cellEffect, Target: TargetOwn, Group: GroupEffects}, addCellEffect(game.EffectDecoy))
+	Register("ADD_SONAR", ActionSpec{Args: []ArgSpec{number("y"), number("turns")}, Group: GroupEffects}, addSonar)
+	Register("REPAIR_CELL", ActionSpec{Args: xy, Target: TargetOwn, Group: GroupEffects}, repairCell)
+
+	Register("COUNT_SHIP_CELLS", ActionSpec{Args: rect, Group: GroupQueries}, countShipCells)
+	Register("IS_SHIP", ActionSpec{Args: xy, Group: GroupQueries}, isShip)
+	Register("DISTANCE_TO_NEAREST_SHIP", ActionSpec{Args: xy, Group: GroupQueries}, distanceToNearestShip)
+	Register("REVEAL_SHIP", ActionSpec{Args: xy, Group: GroupQueries}, revealShip)
+	Register("RANDOM_EMPTY_CELL", ActionSpec{Group: GroupQueries}, randomEmptyCell)
+}
+
+func openCell(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	cmd := &game.OpenCellCommand{Target: target}
+	if err := c.Apply(cmd); err != nil {
+		return err
+	}
+	c.Set("RESULT", boolResult(cmd.Result == "ship"))
+	effect := c.Effect(openCellOutcome(cmd.Result), target)
+	// A sonar on the row reports to scouting as it does to shots.
+	if cmd.Result != "invalid" {
+		if count, ok := c.State.SonarReading(target.Y); ok {
+			effect.Value = &count
+		}
+	}
+	return nil
+}
+
+func makeShot(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	cmd := &game.ShootCommand{Target: target}
+	if err := c.Apply(cmd); err != nil {
+		return err
+	}
+	outcome := OutcomeMiss
+	if cmd.Hit {
+		outcome = OutcomeHit
+	}
+	c.Set("RESULT", boolResult(cmd.Hit))
+	c.Effect(outcome, target)
+	return nil
+}
+
+func setCellStatus(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	status, err := c.String("status")
+	if err != nil {
+		return err
+	}
+	var cellStatus game.CellState
+	switch status {
+	case "water":
+		cellStatus = game.Empty
+	case "ship":
+		cellStatus = game.ShipCell
+	case "shipwreck":
+		cellStatus = game.Hit
+	default:
+		return fmt.Errorf("unknown cell status: %s", status)
+	}
+	if err := c.Apply(&game.SetCellStatusCommand{Target: target, Status: cellStatus}); err != nil {
+		return err
+	}
+	c.Effect(OutcomeStatusSet, target)
+	return nil
+}
+
+func setShipCoordinates(c *Call) error {
+	from, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	to, err := c.Coord("x2", "y2")
+	if err != nil {
+		return err
+	}
+	cmd := &game.MoveShipCommand{From: from, To: to}
+	if err := c.Apply(cmd); err != nil {
+		return err
+	}
+	c.Effect(OutcomeMoved, c.State.Ships[cmd.Backup.ID].Coords...)
+	return nil
+}
+
+func addCellEffect(kind game.EffectKind) ActionHandler {
+	return func(c *Call) error {
+		target, err := c.Coord("x", "y")
+		if err != nil {
+			return err
+		}
+		if err := addEffect(c, kind, target); err != nil {
+			return err
+		}
+		c.Effect(OutcomeEffectAdded, target)
+		return nil
+	}
+}
+
+func addSonar(c *Call) error {
+	y, err := c.Number("y")
+	if err != nil {
+		return err
+	}
+	if err := addEffect(c, game.EffectSonar, game.Coord{Y: int(y)}); err != nil {
+		return err
+	}
+	count := c.State.ShipCellsInRow(int(y))
+	c.Effect(OutcomeEffectAdded).Value = &count
+	return nil
+}
+
+func addEffect(c *Call, kind game.EffectKind, cell game.Coord) error {
+	turns, err := c.Number("turns")
+	if err != nil {
+		return err
+	}
+	return c.Apply(&game.AddEffectCommand{Effect: game.StatusEffect{Kind: kind, Cell: cell, TurnsLeft: int(turns)}})
+}
+
+func countShipCells(c *Call) error {
+	from, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	to, err := c.Coord("x2", "y2")
+	if err != nil {
+		return err
+	}
+	c.Result(c.State.CountShipCells(from, to))
+	return nil
+}
+
+func isShip(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	_, ok := c.State.ShipAt(target)
+	c.Result(int(boolResult(ok)), target)
+	return nil
+}
+
+func distanceToNearestShip(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	d, ok := c.State.NearestShipDistance(target)
+	if !ok {
+		d = -1
+	}
+	c.Result(d, target)
+	return nil
+}
+
+func revealShip(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	ship, ok := c.State.ShipAt(target)
+	if !ok || c.State.Field[target.X][target.Y] != game.Hit {
+		c.Effect(OutcomeNoShip, target)
+		return nil
+	}
+	c.Effect(OutcomeRevealedShip, ship.Coords...)
+	return nil
+}
+
+func randomEmptyCell(c *Call) error {
+	cells := c.State.EmptyCells()
+	if len(cells) == 0 {
+		c.Set("RESULT", 0)
+		c.Effect(OutcomeNoCell)
+		return nil
+	}
+	cell := cells[c.Intn(len(cells))]
+	c.Set("RESULT", 1)
+	c.Set("RESULT_X", float64(cell.X))
+	c.Set("RESULT_Y", float64(cell.Y))
+	c.Effect(OutcomeQueried, cell)
+	return nil
+}
+
+func repairCell(c *Call) error {
+	target, err := c.Coord("x", "y")
+	if err != nil {
+		return err
+	}
+	if err := c.Apply(&game.RepairCellCommand{Target: target}); err != nil {
+		return err
+	}
+	c.Effect(OutcomeRepaired, target)
+	return nil
+}
+
+func endPlayerAction(c *Call) error {
+	c.EndTurn()
+	c.Effect(OutcomeTurnEnded)
+	return nil
+}
+
+func boolResult(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
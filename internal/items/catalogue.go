@@ -0,0 +1,168 @@
+package items
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Catalogue is the designer-facing item file (Items_logic2.json): declared
+// script functions and variables plus the items themselves.
+type Catalogue struct {
+	Functions map[string]FunctionDecl
+	Variables map[string]VariableDecl
+	Items     []Item
+}
+
+type FunctionDecl struct {
+	Input       []string
+	Description string
+}
+
+type VariableDecl struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+func (f *FunctionDecl) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Input       json.RawMessage `json:"input"`
+		Description string          `json:"description"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	f.Description = raw.Description
+	if isNoneArgs(raw.Input) {
+		f.Input = nil
+		return nil
+	}
+	return json.Unmarshal(raw.Input, &f.Input)
+}
+
+type catalogueItem struct {
+	ID          int             `json:"id"`
+	Description string          `json:"description"`
+	Input       string          `json:"input"`
+	Actions     json.RawMessage `json:"actions"`
+}
+
+func LoadCatalogueFile(path string) (*Catalogue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return LoadCatalogue(data)
+}
+
+// LoadCatalogue parses a catalogue and converts every item into an
+// executable Item. Every item declares its own positive ID, which inventories
+// and journals refer to, so reordering the file never changes it.
+func LoadCatalogue(data []byte) (*Catalogue, error) {
+	var raw struct {
+		Functions map[string]FunctionDecl `json:"functions"`
+		Variables map[string]VariableDecl `json:"variables"`
+		Items     json.RawMessage         `json:"items"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	c := &Catalogue{Functions: raw.Functions, Variables: raw.Variables}
+	if len(raw.Items) == 0 {
+		return c, nil
+	}
+
+	// Walk the items object by hand to keep the file order and to know the
+	// line every item and action starts on.
+	topOffsets, topKeys, err := memberOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+	// Keys match as json.Unmarshal matches them: case-insensitively, the
+	// last one winning.
+	itemsStart := -1
+	for i, key := range topKeys {
+		if strings.EqualFold(key, "items") {
+			itemsStart = topOffsets[i]
+		}
+	}
+	if itemsStart < 0 {
+		return nil, errors.New("items object not found")
+	}
+	itemOffsets, names, err := memberOffsets(data[itemsStart:])
+	if err != nil {
+		return nil, err
+	}
+	ids := map[int]string{}
+	for i, name := range names {
+		itemStart := itemsStart + itemOffsets[i]
+		var ci catalogueItem
+		if err := json.NewDecoder(bytes.NewReader(data[itemStart:])).Decode(&ci); err != nil {
+			return nil, fmt.Errorf("item %s: %w", name, err)
+		}
+		if ci.ID <= 0 {
+			return nil, fmt.Errorf("item %s (line %d): missing or non-positive id", name, lineAt(data, itemStart))
+		}
+		if other, dup := ids[ci.ID]; dup {
+			return nil, fmt.Errorf("item %s (line %d): id %d is taken by %s", name, lineAt(data, itemStart), ci.ID, other)
+		}
+		ids[ci.ID] = name
+		actions, err := ParseScript(string(ci.Actions))
+		if err != nil {
+			return nil, fmt.Errorf("item %s (line %d): %w", name, lineAt(data, itemStart), err)
+		}
+		fieldOffsets, fields, err := memberOffsets(data[itemStart:])
+		if err != nil {
+			return nil, err
+		}
+		for j, field := range fields {
+			if strings.EqualFold(field, "actions") {
+				setActionLines(actions, data, itemStart+fieldOffsets[j])
+			}
+		}
+		var script bytes.Buffer
+		if err := json.Compact(&script, ci.Actions); err != nil {
+			return nil, fmt.Errorf("item %s: %w", name, err)
+		}
+		c.Items = append(c.Items, Item{
+			ID:          ci.ID,
+			Name:        name,
+			Kind:        inferKind(actions),
+			Description: ci.Description,
+			Input:       ci.Input,
+			Script:      script.String(),
+			Actions:     actions,
+			Line:        lineAt(data, itemStart),
+		})
+	}
+	stampVersion(c.Items)
+	return c, nil
+}
+
+func (c *Catalogue) ItemByName(name string) (Item, bool) {
+	for _, item := range c.Items {
+		if item.Name == name {
+			return item, true
+		}
+	}
+	return Item{}, false
+}
+
+// inferKind derives the item kind, which the catalogue does not declare, from
+// the target boards of the actions the item uses.
+func inferKind(actions []Action) string {
+	for _, a := range actions {
+		if spec, ok := LookupAction(a.Name); ok && spec.Target == TargetOwn {
+			return KindDefense
+		}
+		for _, branch := range a.Branches {
+			if inferKind(branch) == KindDefense {
+				return KindDefense
+			}
+		}
+	}
+	return KindAttack
+}
@@ -0,0 +1,95 @@
+package items
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"strings"
+	"testing"
+)
+
+func TestLoadCatalogue(t *testing.T) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"Крест Нахимова", "Ремонтный набор", "Боевой приказ", "Конь", "Ладья", "Слон", "Ферзь"}
+	if len(catalogue.Items) != len(names) {
+		t.Fatalf("got %d items, want %d", len(catalogue.Items), len(names))
+	}
+	for i, name := range names {
+		if catalogue.Items[i].Name != name || catalogue.Items[i].ID != i+1 {
+			t.Errorf("item %d: got %q (id %d), want %q", i, catalogue.Items[i].Name, catalogue.Items[i].ID, name)
+		}
+	}
+
+	if got := catalogue.Functions["SET_SHIP_COORDINATES"].Input; len(got) != 4 {
+		t.Errorf("SET_SHIP_COORDINATES input = %v", got)
+	}
+	if got := catalogue.Functions["END_PLAYER_ACTION"].Input; got != nil {
+		t.Errorf("END_PLAYER_ACTION input = %v, want none", got)
+	}
+	if catalogue.Variables["FIELD_SIZE"].Type != "int" {
+		t.Errorf("FIELD_SIZE = %+v", catalogue.Variables["FIELD_SIZE"])
+	}
+
+	cross, _ := catalogue.ItemByName("Крест Нахимова")
+	state := game.NewGameState()
+	res, err := UseItem(cross.ID, state, catalogue.Items, map[string]interface{}{"x": 5, "y": 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Effects) != 6 || !res.EndsTurn || res.Last() != OutcomeTurnEnded {
+		t.Errorf("result = %+v", res)
+	}
+	if e := res.Effects[1]; e.Action != "OPEN_CELL" || e.Outcome != OutcomeRevealedEmpty || len(e.Cells) != 1 || e.Cells[0] != (game.Coord{X: 5, Y: 6}) {
+		t.Errorf("second effect = %+v", e)
+	}
+	for _, c := range []game.Coord{{X: 5, Y: 5}, {X: 5, Y: 6}, {X: 6, Y: 5}, {X: 5, Y: 4}, {X: 4, Y: 5}} {
+		if state.Field[c.X][c.Y] != game.Revealed {
+			t.Errorf("cell %v not revealed", c)
+		}
+	}
+}
+
+func TestLoadCatalogue_IDs(t *testing.T) {
+	catalogue := func(items string) string {
+		return `{"functions": {}, "items": {` + items + `}}`
+	}
+	first := `"a": {"id": 2, "actions": []}`
+	second := `"b": {"id": 1, "actions": []}`
+
+	// Reordering the file keeps every item's ID.
+	for _, items := range []string{first + ", " + second, second + ", " + first} {
+		c, err := LoadCatalogue([]byte(catalogue(items)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a, _ := c.ItemByName("a"); a.ID != 2 {
+			t.Errorf("a has id %d", a.ID)
+		}
+		if b, _ := c.ItemByName("b"); b.ID != 1 {
+			t.Errorf("b has id %d", b.ID)
+		}
+	}
+
+	for _, tc := range []struct{ items, want string }{
+		{`"a": {"actions": []}`, "missing or non-positive id"},
+		{first + `, "c": {"id": 2, "actions": []}`, "id 2 is taken by a"},
+	} {
+		if _, err := LoadCatalogue([]byte(catalogue(tc.items))); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("err = %v, want %q", err, tc.want)
+		}
+	}
+}
+
+func TestLoadCatalogue_KeyCase(t *testing.T) {
+	c, err := LoadCatalogue([]byte(`{"Items": {"a": {"id": 1, "Actions": [
+		{"OPEN_CELL": {"x": "1", "y": "1"}}
+	]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Items) != 1 || c.Items[0].ID != 1 || len(c.Items[0].Actions) != 1 || c.Items[0].Actions[0].Line != 2 {
+		t.Errorf("items = %+v", c.Items)
+	}
+}
@@ -0,0 +1,335 @@
+package items
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"lesta-battleship/server-core/internal/transaction"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Program is an item script compiled once: every expression is parsed and
+// every action resolved to its handler, so running it does no parsing.
+// Programs are read-only and may be run concurrently.
+type Program struct {
+	ops []*op
+	// actions are the registered actions the program uses, for game modes
+	// to check.
+	actions []string
+}
+
+// handler executes a compiled action.
+type handler func(r *scriptRun, o *op) error
+
+// op is a compiled action.
+type op struct {
+	Action
+	name     string
+	handler  handler // nil for unknown actions, which fail when reached
+	board    transaction.Board
+	args     []compiledArg
+	branches map[string][]*op
+}
+
+// compiledArg is an action argument ready to be evaluated.
+type compiledArg struct {
+	key     string
+	expr    *Expr
+	param   string // a "$name" string argument
+	literal interface{}
+	// fallback marks an input missing from the action, taken from the
+	// script param of the same name when there is one.
+	fallback bool
+}
+
+func (a *compiledArg) eval(env *evalEnv) (interface{}, error) {
+	switch {
+	case a.expr != nil:
+		return a.expr.eval(env)
+	case a.param != "":
+		if val, ok := env.params[a.param]; ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("unknown variable %s", a.param)
+	}
+	return a.literal, nil
+}
+
+func Compile(actions []Action) (*Program, error) {
+	ops, err := compileActions(actions)
+	if err != nil {
+		return nil, err
+	}
+	p := &Program{ops: ops}
+	seen := map[string]bool{}
+	p.collect(ops, seen)
+	return p, nil
+}
+
+func (p *Program) collect(ops []*op, seen map[string]bool) {
+	for _, o := range ops {
+		if _, ok := lookupAction(o.name); ok && !seen[o.name] {
+			seen[o.name] = true
+			p.actions = append(p.actions, o.name)
+		}
+		for _, branch := range o.branches {
+			p.collect(branch, seen)
+		}
+	}
+}
+
+// Check reports the first action of the program that set does not allow.
+func (p *Program) Check(set ActionSet) error {
+	for _, name := range p.actions {
+		if err := set.check(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompileItem compiles the item's actions, parsing its script first when the
+// item comes without them.
+func CompileItem(item Item) (*Program, error) {
+	actions := item.Actions
+	if actions == nil {
+		parsed, err := ParseScript(item.Script)
+		if err != nil {
+			return nil, err
+		}
+		actions = parsed
+	}
+	return Compile(actions)
+}
+
+func compileActions(actions []Action) ([]*op, error) {
+	ops := make([]*op, len(actions))
+	for i, a := range actions {
+		o, err := compileAction(a)
+		if err != nil {
+			return nil, err
+		}
+		ops[i] = o
+	}
+	return ops, nil
+}
+
+func compileAction(a Action) (*op, error) {
+	o := &op{Action: a, name: strings.ToUpper(a.Name)}
+	o.handler = constructs[o.name]
+	registered, ok := lookupAction(o.name)
+	if ok {
+		o.handler = call(registered.handler)
+	}
+	o.board = registered.spec.Target.Board()
+
+	raw := make(map[string]interface{}, len(a.Args))
+	for k, v := range a.Args {
+		raw[k] = v
+	}
+	fallback := map[string]bool{}
+	for _, in := range registered.spec.inputs() {
+		if _, ok := raw[in]; !ok {
+			raw[in] = "$" + in
+			fallback[in] = true
+		}
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	// Keys are evaluated in sorted order so that PREV_RAND in "y" sees the
+	// RAND drawn for "x".
+	sort.Strings(keys)
+	for _, k := range keys {
+		spec, _ := registered.spec.arg(k)
+		arg, err := compileArg(k, raw[k], spec.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %s: %w", a.Name, k, err)
+		}
+		arg.fallback = fallback[k]
+		o.args = append(o.args, arg)
+	}
+
+	if len(a.Branches) > 0 {
+		o.branches = make(map[string][]*op, len(a.Branches))
+		for key, branch := range a.Branches {
+			ops, err := compileActions(branch)
+			if err != nil {
+				return nil, err
+			}
+			o.branches[key] = ops
+		}
+	}
+	return o, nil
+}
+
+// call adapts a registered handler to the arguments of a compiled action.
+func call(h ActionHandler) handler {
+	return func(r *scriptRun, o *op) error {
+		args, err := r.args(o)
+		if err != nil {
+			return err
+		}
+		state, err := r.board(o.board)
+		if err != nil {
+			return err
+		}
+		return h(&Call{Name: o.name, Args: args, State: state, board: o.board, run: r})
+	}
+}
+
+func compileArg(key string, raw interface{}, typ ArgType) (compiledArg, error) {
+	arg := compiledArg{key: key}
+	switch v := raw.(type) {
+	case string:
+		if typ == ArgString {
+			if strings.HasPrefix(v, "$") {
+				arg.param = v[1:]
+			} else {
+				arg.literal = v
+			}
+			return arg, nil
+		}
+		e, err := ParseExpr(v)
+		if err != nil {
+			return arg, err
+		}
+		arg.expr = e
+	case map[string]interface{}:
+		// Object form of a call, e.g. {"RAND": "None"}.
+		if len(v) != 1 {
+			return arg, fmt.Errorf("invalid call %v", v)
+		}
+		for name := range v {
+			e, err := ParseExpr(name + "()")
+			if err != nil {
+				return arg, err
+			}
+			arg.expr = e
+		}
+	default:
+		if f, ok := toFloat(raw); ok {
+			arg.literal = f
+		} else {
+			arg.literal = raw
+		}
+	}
+	return arg, nil
+}
+
+func (o *op) arg(key string) *compiledArg {
+	for i := range o.args {
+		if o.args[i].key == key {
+			return &o.args[i]
+		}
+	}
+	return nil
+}
+
+// evalArgs evaluates all arguments of o. Inputs missing from the action
+// default to the script param of the same name, e.g. x and y of
+// SET_CELL_STATUS in "Ремонтный набор".
+func (o *op) evalArgs(env *evalEnv) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(o.args))
+	for i := range o.args {
+		a := &o.args[i]
+		if a.fallback {
+			if _, ok := env.params[a.key]; !ok {
+				continue
+			}
+		}
+		val, err := a.eval(env)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %s: %w", o.Name, a.key, err)
+		}
+		args[a.key] = val
+	}
+	return args, nil
+}
+
+// CatalogueVersion identifies the content of a list of items, so compiled
+// programs can be cached across reloads that change nothing.
+func CatalogueVersion(list []Item) string {
+	h := sha256.New()
+	for _, item := range list {
+		h.Write([]byte(strconv.Itoa(item.ID)))
+		h.Write([]byte{0})
+		h.Write([]byte(item.Script))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// stampVersion sets the catalogue version on every item of list.
+func stampVersion(list []Item) {
+	version := CatalogueVersion(list)
+	for i := range list {
+		list[i].Version = version
+	}
+}
+
+// ProgramCache keeps compiled programs by item ID and catalogue version. Only
+// the two most recent versions are kept, so a reload does not evict the
+// programs rooms are still using right away.
+type ProgramCache struct {
+	mu       sync.Mutex
+	programs map[programKey]*Program
+	versions []string
+}
+
+type programKey struct {
+	version string
+	id      int
+}
+
+func NewProgramCache() *ProgramCache {
+	return &ProgramCache{programs: map[programKey]*Program{}}
+}
+
+// Programs is the cache runtimes use unless they are given their own.
+var Programs = NewProgramCache()
+
+// Program returns the compiled program of item, compiling it on first use.
+// Items that carry no catalogue version are compiled on every call.
+func (c *ProgramCache) Program(item Item) (*Program, error) {
+	if item.Version == "" {
+		return CompileItem(item)
+	}
+	key := programKey{version: item.Version, id: item.ID}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.programs[key]; ok {
+		return p, nil
+	}
+	p, err := CompileItem(item)
+	if err != nil {
+		return nil, err
+	}
+	c.addVersion(item.Version)
+	c.programs[key] = p
+	return p, nil
+}
+
+func (c *ProgramCache) addVersion(version string) {
+	for _, v := range c.versions {
+		if v == version {
+			return
+		}
+	}
+	c.versions = append(c.versions, version)
+	if len(c.versions) <= 2 {
+		return
+	}
+	evicted := c.versions[0]
+	c.versions = c.versions[1:]
+	for key := range c.programs {
+		if key.version == evicted {
+			delete(c.programs, key)
+		}
+	}
+}
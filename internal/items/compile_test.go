@@ -0,0 +1,108 @@
+package items
+
+import (
+	"context"
+	"lesta-battleship/server-core/internal/game"
+	"testing"
+)
+
+// fixedRNG always draws the same number, so benchmarks do not keep a draw log.
+type fixedRNG int
+
+func (r fixedRNG) Intn(n int) int { return int(r) % n }
+
+func TestProgramCache(t *testing.T) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	item, _ := catalogue.ItemByName("Слон")
+	if item.Version == "" {
+		t.Fatal("LoadCatalogue did not stamp a version")
+	}
+
+	cache := NewProgramCache()
+	p1, err := cache.Program(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, _ := cache.Program(item)
+	if p1 != p2 {
+		t.Error("second lookup compiled the item again")
+	}
+
+	// A new catalogue version compiles anew; the oldest of three is evicted.
+	for _, version := range []string{"v2", "v3"} {
+		item.Version = version
+		if p, _ := cache.Program(item); p == p1 {
+			t.Errorf("version %s reused the program of another version", version)
+		}
+	}
+	item.Version = catalogue.Items[0].Version
+	if p, _ := cache.Program(item); p == p1 {
+		t.Error("program of an evicted version was kept")
+	}
+
+	if _, err := cache.Program(Item{Script: `[{"OPEN_CELL": {"x": "1 +", "y": "1"}}]`}); err == nil {
+		t.Error("expected a compile error for a malformed expression")
+	}
+}
+
+func TestCatalogueVersion(t *testing.T) {
+	list := []Item{{ID: 1, Script: `[{"END_PLAYER_ACTION": "None"}]`}}
+	v1 := CatalogueVersion(list)
+	if CatalogueVersion(list) != v1 {
+		t.Error("version is not stable")
+	}
+	list[0].Script = `[]`
+	if CatalogueVersion(list) == v1 {
+		t.Error("version did not change with the script")
+	}
+}
+
+func TestRunActions_CompileErrorRunsNothing(t *testing.T) {
+	state := game.NewGameState()
+	actions := mustParse(t, `[{"OPEN_CELL": {"x": "1", "y": "1"}}, {"OPEN_CELL": {"x": "(", "y": "1"}}]`)
+	if _, err := RunActions(context.Background(), actions, state, nil); err == nil {
+		t.Fatal("expected a compile error")
+	}
+	if state.Field[1][1] != game.Empty {
+		t.Error("an action ran before the script failed to compile")
+	}
+}
+
+// The uncached benchmarks use items that carry only their script and no
+// version, so every use parses and compiles the script again, as it did
+// before programs were cached.
+func benchmarkUseItem(b *testing.B, name string, cached bool) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		b.Fatal(err)
+	}
+	item, _ := catalogue.ItemByName(name)
+	if !cached {
+		item = Item{ID: item.ID, Script: item.Script}
+	}
+	list := []Item{item}
+	rt := &Runtime{RNG: fixedRNG(3), Programs: NewProgramCache()}
+	params := map[string]interface{}{"x": 4, "y": 4, "direction": 1}
+	base := game.NewGameState()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state := base.Clone()
+		if _, err := rt.UseItem(context.Background(), item.ID, state, list, params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUseItem_CrossUncached(b *testing.B) {
+	benchmarkUseItem(b, "Крест Нахимова", false)
+}
+func BenchmarkUseItem_CrossCached(b *testing.B) {
+	benchmarkUseItem(b, "Крест Нахимова", true)
+}
+func BenchmarkUseItem_BishopUncached(b *testing.B) { benchmarkUseItem(b, "Слон", false) }
+func BenchmarkUseItem_BishopCached(b *testing.B)   { benchmarkUseItem(b, "Слон", true) }
@@ -0,0 +1,167 @@
+package items
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// controlBodies lists the control actions and the keys of their nested
+// action lists, which are kept in Action.Branches:
+//
+//	{"IF": {"cond": "RESULT == 1", "then": [...], "else": [...]}}
+//	{"LET": {"n": "x + 1"}}
+//	{"REPEAT": {"times": "5", "do": [...]}}
+//	{"FOR": {"var": "i", "from": "0", "to": "FIELD_SIZE-1", "step": "1", "do": [...]}}
+var controlBodies = map[string][]string{
+	"IF":     {"then", "else"},
+	"LET":    nil,
+	"REPEAT": {"do"},
+	"FOR":    {"do"},
+}
+
+// controlArgs are the scalar arguments of each control action, required
+// ones first.
+var controlArgs = map[string]struct{ required, optional []string }{
+	"IF":     {required: []string{"cond"}},
+	"REPEAT": {required: []string{"times"}},
+	"FOR":    {required: []string{"var", "from", "to"}, optional: []string{"step"}},
+}
+
+func isControl(name string) bool {
+	_, ok := controlBodies[name]
+	return ok
+}
+
+// parseControl splits the args of a control action into scalar arguments and
+// nested action lists.
+func parseControl(a *Action, raw json.RawMessage) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return fmt.Errorf("args must be an object: %w", err)
+	}
+	a.Args = map[string]interface{}{}
+	for key, val := range obj {
+		if isBody(a.Name, key) {
+			body, err := parseActionList(val)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			if a.Branches == nil {
+				a.Branches = map[string][]Action{}
+			}
+			a.Branches[key] = body
+			continue
+		}
+		var arg interface{}
+		if err := json.Unmarshal(val, &arg); err != nil {
+			return fmt.Errorf("argument %s: %w", key, err)
+		}
+		a.Args[key] = arg
+	}
+	return nil
+}
+
+func isBody(name, key string) bool {
+	for _, k := range controlBodies[strings.ToUpper(name)] {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *scriptRun) number(o *op, key string) (float64, error) {
+	arg := o.arg(key)
+	if arg == nil {
+		return 0, fmt.Errorf("%s: missing argument %s", o.Name, key)
+	}
+	val, err := arg.eval(r.env)
+	if err != nil {
+		return 0, fmt.Errorf("%s: argument %s: %w", o.Name, key, err)
+	}
+	f, ok := toFloat(val)
+	if !ok {
+		return 0, fmt.Errorf("%s: argument %s: not a number", o.Name, key)
+	}
+	if r.tracer != nil {
+		r.tracer.arg(key, f)
+	}
+	return f, nil
+}
+
+func runIf(r *scriptRun, o *op) error {
+	cond, err := r.number(o, "cond")
+	if err != nil {
+		return err
+	}
+	if cond != 0 {
+		return r.run(o.branches["then"])
+	}
+	return r.run(o.branches["else"])
+}
+
+// runLet assigns variables in key order, like arguments are evaluated.
+func runLet(r *scriptRun, o *op) error {
+	for _, a := range o.args {
+		val, err := r.number(o, a.key)
+		if err != nil {
+			return err
+		}
+		r.env.set(a.key, val)
+	}
+	return nil
+}
+
+func runRepeat(r *scriptRun, o *op) error {
+	times, err := r.number(o, "times")
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(times); i++ {
+		if err := r.budget.action(); err != nil {
+			return err
+		}
+		if err := r.run(o.branches["do"]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runFor(r *scriptRun, o *op) error {
+	v, _ := o.Args["var"].(string)
+	if !isIdent(v) {
+		return fmt.Errorf("%s: invalid loop variable %v", o.Name, o.Args["var"])
+	}
+	from, err := r.number(o, "from")
+	if err != nil {
+		return err
+	}
+	to, err := r.number(o, "to")
+	if err != nil {
+		return err
+	}
+	step := 1.0
+	if from > to {
+		step = -1
+	}
+	if o.arg("step") != nil {
+		if step, err = r.number(o, "step"); err != nil {
+			return err
+		}
+		if step == 0 {
+			return fmt.Errorf("%s: step must not be zero", o.Name)
+		}
+	}
+	for i := from; (step > 0 && i <= to) || (step < 0 && i >= to); i += step {
+		if err := r.budget.action(); err != nil {
+			return err
+		}
+		r.env.set(v, i)
+		if err := r.run(o.branches["do"]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
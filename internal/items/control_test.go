@@ -0,0 +1,219 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"strings"
+	"testing"
+)
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		params map[string]interface{}
+		opened []game.Coord
+		shots  []game.Coord
+	}{
+		{
+			name: "if on action result",
+			script: `[
+				{"OPEN_CELL": {"x": "x", "y": "y"}},
+				{"IF": {"cond": "RESULT", "then": {"MAKE_SHOT": {"x": "x", "y": "y"}}, "else": {"OPEN_CELL": {"x": "x+1", "y": "y"}}}}
+			]`,
+			params: map[string]interface{}{"x": 1, "y": 1},
+			shots:  []game.Coord{{X: 1, Y: 1}},
+		},
+		{
+			name: "else branch",
+			script: `[
+				{"OPEN_CELL": {"x": "x", "y": "y"}},
+				{"IF": {"cond": "RESULT == 1", "then": {"MAKE_SHOT": {"x": "x", "y": "y"}}, "else": [{"OPEN_CELL": {"x": "x+1", "y": "y"}}]}}
+			]`,
+			params: map[string]interface{}{"x": 5, "y": 5},
+			opened: []game.Coord{{X: 5, Y: 5}, {X: 6, Y: 5}},
+		},
+		{
+			name: "if without else",
+			script: `[
+				{"IF": {"cond": "x > 3", "then": {"OPEN_CELL": {"x": "x", "y": "y"}}}}
+			]`,
+			params: map[string]interface{}{"x": 2, "y": 2},
+		},
+		{
+			name: "let",
+			script: `[
+				{"LET": {"a": "x + 1", "b": "a * 2"}},
+				{"OPEN_CELL": {"x": "a", "y": "b"}}
+			]`,
+			params: map[string]interface{}{"x": 2},
+			opened: []game.Coord{{X: 3, Y: 6}},
+		},
+		{
+			name: "non-ascii names",
+			script: `[
+				{"LET": {"сдвиг": "x + 1"}},
+				{"FOR": {"var": "ряд", "from": "0", "to": "1", "do": [{"OPEN_CELL": {"x": "сдвиг", "y": "ряд"}}]}}
+			]`,
+			params: map[string]interface{}{"x": 2},
+			opened: []game.Coord{{X: 3, Y: 0}, {X: 3, Y: 1}},
+		},
+		{
+			name: "repeat",
+			script: `[
+				{"LET": {"n": "0"}},
+				{"REPEAT": {"times": "3", "do": [{"OPEN_CELL": {"x": "n", "y": "0"}}, {"LET": {"n": "n + 2"}}]}}
+			]`,
+			opened: []game.Coord{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 4, Y: 0}},
+		},
+		{
+			name:   "for",
+			script: `[{"FOR": {"var": "i", "from": "y", "to": "y + 2", "do": {"OPEN_CELL": {"x": "9", "y": "i"}}}}]`,
+			params: map[string]interface{}{"y": 4},
+			opened: []game.Coord{{X: 9, Y: 4}, {X: 9, Y: 5}, {X: 9, Y: 6}},
+		},
+		{
+			name:   "for counting down with step",
+			script: `[{"FOR": {"var": "i", "from": "8", "to": "0", "step": "-4", "do": {"OPEN_CELL": {"x": "i", "y": "i"}}}}]`,
+			opened: []game.Coord{{X: 8, Y: 8}, {X: 4, Y: 4}, {X: 0, Y: 0}},
+		},
+		{
+			name: "nested loops",
+			script: `[{"FOR": {"var": "i", "from": "0", "to": "1", "do":
+				{"FOR": {"var": "j", "from": "0", "to": "1", "do": {"OPEN_CELL": {"x": "5+i", "y": "5+j"}}}}
+			}}]`,
+			opened: []game.Coord{{X: 5, Y: 5}, {X: 5, Y: 6}, {X: 6, Y: 5}, {X: 6, Y: 6}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := game.NewGameState()
+			state.Ships["s"] = game.Ship{ID: "s", Type: game.Submarine, Coords: []game.Coord{{X: 1, Y: 1}}}
+			state.Field[1][1] = game.ShipCell
+
+			res, err := RunScript(context.Background(), tt.script, state, tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var opened, shots []game.Coord
+			for _, e := range res.Effects {
+				switch e.Action {
+				case "OPEN_CELL":
+					if e.Outcome == OutcomeRevealedEmpty {
+						opened = append(opened, e.Cells...)
+					}
+				case "MAKE_SHOT":
+					shots = append(shots, e.Cells...)
+				}
+			}
+			if !sameCoords(opened, tt.opened) || !sameCoords(shots, tt.shots) {
+				t.Errorf("opened %v shot %v, want %v and %v", opened, shots, tt.opened, tt.shots)
+			}
+		})
+	}
+}
+
+func sameCoords(a, b []game.Coord) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestControl_StepLimit(t *testing.T) {
+	scripts := []string{
+		`[{"REPEAT": {"times": "1000000", "do": {"OPEN_CELL": {"x": "0", "y": "0"}}}}]`,
+		`[{"REPEAT": {"times": "1000000", "do": []}}]`,
+		`[{"FOR": {"var": "i", "from": "0", "to": "1000000", "do": {"LET": {"a": "i"}}}}]`,
+	}
+	for _, script := range scripts {
+		state := game.NewGameState()
+		_, err := RunScript(context.Background(), `[{"OPEN_CELL": {"x": "5", "y": "5"}}, `+script[1:], state, nil)
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) {
+			t.Errorf("%s: got %v, want a limit error", script, err)
+		}
+		if state.Field[5][5] != game.Empty || state.Field[0][0] != game.Empty {
+			t.Errorf("%s: state not rolled back", script)
+		}
+	}
+}
+
+func TestControl_Errors(t *testing.T) {
+	for _, script := range []string{
+		`[{"IF": {"then": {"END_PLAYER_ACTION": "None"}}}]`,
+		`[{"FOR": {"var": "1i", "from": "0", "to": "1", "do": []}}]`,
+		`[{"FOR": {"var": "i", "from": "0", "to": "1", "step": "0", "do": []}}]`,
+		`[{"LET": {"a": "b"}}]`,
+	} {
+		if _, err := RunScript(context.Background(), script, game.NewGameState(), nil); err == nil {
+			t.Errorf("expected %s to fail", script)
+		}
+	}
+}
+
+func TestValidate_Control(t *testing.T) {
+	valid := `[
+		{"FOR": {"var": "i", "from": "0", "to": "FIELD_SIZE-1", "do": [
+			{"IS_SHIP": {"x": "i", "y": "y"}},
+			{"IF": {"cond": "RESULT", "then": {"LET": {"found": "i"}}}}
+		]}},
+		{"LET": {"ряд": "y + 1"}},
+		{"OPEN_CELL": {"x": "found", "y": "ряд"}}
+	]`
+	if diags := Validate(Item{Name: "valid", Actions: mustParse(t, valid)}); len(diags) != 0 {
+		t.Errorf("unexpected diagnostics: %v", diags)
+	}
+
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{`[{"IF": {"then": {"END_PLAYER_ACTION": "None"}}}]`, "IF: missing argument cond"},
+		{`[{"REPEAT": {"times": "3", "count": "1", "do": []}}]`, "REPEAT: unexpected argument count"},
+		{`[{"REPEAT": {"times": "3"}}]`, "REPEAT: do is empty"},
+		{`[{"FOR": {"var": "1i", "from": "0", "to": "1", "do": []}}]`, "FOR: invalid loop variable 1i"},
+		{`[{"LET": {"a": "b + 1"}}]`, "LET: argument a: unknown variable b"},
+		{`[{"LET": {"ход-1": "1"}}]`, `LET: invalid variable name "ход-1"`},
+		{`[{"IF": {"cond": "x", "then": {"OPEN_CEL": {}}}}]`, "unknown function OPEN_CEL"},
+	}
+	for _, tt := range tests {
+		diags := Validate(Item{Name: "test", Actions: mustParse(t, tt.script)})
+		found := false
+		for _, d := range diags {
+			found = found || strings.Contains(d.Msg, tt.want)
+		}
+		if !found {
+			t.Errorf("%s: want %q, got %v", tt.script, tt.want, diags)
+		}
+	}
+}
+
+func TestValidate_ControlLines(t *testing.T) {
+	catalogue, err := LoadCatalogue([]byte(`{
+  "functions": {"OPEN_CELL": {"input": ["x", "y"]}},
+  "items": {
+    "test": {"id": 1,
+      "actions": [
+        { "REPEAT": {"times": "2", "do": [
+          { "OPEN_CELL": {"x": "x", "y": "y"} },
+          { "MAKE_SHOT": {"x": "x", "y": "y"} }
+        ]} }
+      ]
+    }
+  }
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := catalogue.Validate()
+	if len(diags) != 1 || diags[0].Line != 8 {
+		t.Errorf("diagnostics = %v, want one on line 8", diags)
+	}
+}
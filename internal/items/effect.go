@@ -0,0 +1,59 @@
+package items
+
+import "lesta-battleship/server-core/internal/game"
+
+// Outcomes of item actions.
+const (
+	OutcomeRevealedEmpty = "revealed_empty"
+	OutcomeRevealedShip  = "revealed_ship"
+	OutcomeAlreadyOpen   = "already_open"
+	OutcomeOutOfBounds   = "out_of_bounds"
+	OutcomeHit           = "hit"
+	OutcomeMiss          = "miss"
+	OutcomeStatusSet     = "status_set"
+	OutcomeMoved         = "moved"
+	OutcomeTurnEnded     = "turn_ended"
+	OutcomeEffectAdded   = "effect_added"
+	OutcomeQueried       = "queried"
+	OutcomeNoShip        = "no_ship"
+	OutcomeNoCell        = "no_cell"
+	OutcomeRepaired      = "repaired"
+)
+
+// Effect is what a single action of an item script did.
+type Effect struct {
+	Action  string                 `json:"action"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+	Cells   []game.Coord           `json:"cells,omitempty"`
+	Outcome string                 `json:"outcome"`
+	// Own marks an action on the user's own board rather than the enemy's.
+	Own bool `json:"own,omitempty"`
+	// Value carries what the action reported, e.g. a sonar's ship count.
+	Value *int `json:"value,omitempty"`
+}
+
+// Result is the effect log of an item use, one entry per executed action.
+type Result struct {
+	Effects  []Effect `json:"effects"`
+	EndsTurn bool     `json:"ends_turn"`
+}
+
+// Last returns the outcome of the last action, or "" for an empty script.
+func (r Result) Last() string {
+	if len(r.Effects) == 0 {
+		return ""
+	}
+	return r.Effects[len(r.Effects)-1].Outcome
+}
+
+func openCellOutcome(result string) string {
+	switch result {
+	case "empty":
+		return OutcomeRevealedEmpty
+	case "ship":
+		return OutcomeRevealedShip
+	case "invalid":
+		return OutcomeOutOfBounds
+	}
+	return OutcomeAlreadyOpen
+}
@@ -0,0 +1,572 @@
+package items
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExprError reports a problem in an item script expression. Pos is the
+// 1-based column of the offending token, counted in runes.
+type ExprError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("%s at column %d in %q", e.Msg, e.Pos, e.Expr)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentRune reports whether r may follow the first rune of a name.
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || r >= '0' && r <= '9'
+}
+
+// isIdent reports whether s is a name an expression can refer to, as LET and
+// FOR must define.
+func isIdent(s string) bool {
+	if !isIdentStart(s) {
+		return false
+	}
+	for _, r := range s {
+		if !isIdentRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenize(src string) ([]token, error) {
+	var toks []token
+	// column is the 1-based rune column of the byte offset at.
+	column := func(at int) int { return utf8.RuneCountInString(src[:at]) + 1 }
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, &ExprError{Expr: src, Pos: column(start), Msg: fmt.Sprintf("invalid number %q", src[start:i])}
+			}
+			toks = append(toks, token{kind: tokNumber, text: src[start:i], num: n, pos: column(start)})
+		case c == '$' || isIdentStart(src[i:]):
+			start := i
+			if c == '$' {
+				i++
+			}
+			nameStart := i
+			for i < len(src) {
+				r, w := utf8.DecodeRuneInString(src[i:])
+				if !isIdentRune(r) {
+					break
+				}
+				i += w
+			}
+			if i == nameStart {
+				return nil, &ExprError{Expr: src, Pos: column(start), Msg: "expected variable name after '$'"}
+			}
+			toks = append(toks, token{kind: tokIdent, text: src[nameStart:i], pos: column(start)})
+		case c == '{':
+			// Legacy call syntax: {'RAND':'None'} or {"RAND":"None"}.
+			end := strings.IndexByte(src[i:], '}')
+			if end < 0 {
+				return nil, &ExprError{Expr: src, Pos: column(i), Msg: "unterminated '{'"}
+			}
+			name, ok := legacyCall(src[i+1 : i+end])
+			if !ok {
+				return nil, &ExprError{Expr: src, Pos: column(i), Msg: fmt.Sprintf("invalid call %q", src[i:i+end+1])}
+			}
+			toks = append(toks,
+				token{kind: tokIdent, text: name, pos: column(i)},
+				token{kind: tokLParen, text: "(", pos: column(i)},
+				token{kind: tokRParen, text: ")", pos: column(i)},
+			)
+			i += end + 1
+		case c == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: column(i)})
+			i++
+		case c == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: column(i)})
+			i++
+		case c == ',':
+			toks = append(toks, token{kind: tokComma, text: ",", pos: column(i)})
+			i++
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "+", "-", "*", "/", "%", "<", ">", "!"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, &ExprError{Expr: src, Pos: column(i), Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			toks = append(toks, token{kind: tokOp, text: op, pos: column(i)})
+			i += len(op)
+		}
+	}
+	return append(toks, token{kind: tokEOF, pos: column(len(src))}), nil
+}
+
+// legacyCall parses the body of {'NAME':'None'}.
+func legacyCall(body string) (string, bool) {
+	parts := strings.SplitN(body, ":", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+	name := strings.Trim(strings.TrimSpace(parts[0]), `'"`)
+	arg := strings.Trim(strings.TrimSpace(parts[1]), `'"`)
+	if name == "" || arg != "None" {
+		return "", false
+	}
+	return name, true
+}
+
+type node interface {
+	eval(env *evalEnv) (float64, error)
+}
+
+type numberNode struct {
+	val float64
+}
+
+type varNode struct {
+	name string
+	pos  int
+}
+
+type unaryNode struct {
+	op  string
+	x   node
+	pos int
+}
+
+type binaryNode struct {
+	op   string
+	l, r node
+	pos  int
+}
+
+type callNode struct {
+	name string
+	args []node
+	pos  int
+}
+
+// Expr is a parsed item script expression.
+type Expr struct {
+	src  string
+	root node
+}
+
+func ParseExpr(src string) (*Expr, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: src, toks: toks}
+	if p.peek().kind == tokEOF {
+		return nil, &ExprError{Expr: src, Pos: 1, Msg: "empty expression"}
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func (e *Expr) String() string {
+	return e.src
+}
+
+type exprParser struct {
+	src  string
+	toks []token
+	i    int
+}
+
+func (p *exprParser) peek() token {
+	return p.toks[p.i]
+}
+
+func (p *exprParser) next() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *exprParser) errorf(t token, format string, args ...interface{}) error {
+	return &ExprError{Expr: p.src, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) acceptOp(ops ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return t, false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.next()
+			return t, true
+		}
+	}
+	return t, false
+}
+
+func (p *exprParser) parseBinary(sub func() (node, error), ops ...string) (node, error) {
+	l, err := sub()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.acceptOp(ops...)
+		if !ok {
+			return l, nil
+		}
+		r, err := sub()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{op: t.text, l: l, r: r, pos: t.pos}
+	}
+}
+
+func (p *exprParser) parseOr() (node, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *exprParser) parseAnd() (node, error) {
+	return p.parseBinary(p.parseCompare, "&&")
+}
+
+func (p *exprParser) parseCompare() (node, error) {
+	return p.parseBinary(p.parseAdd, "==", "!=", "<=", ">=", "<", ">")
+}
+
+func (p *exprParser) parseAdd() (node, error) {
+	return p.parseBinary(p.parseMul, "+", "-")
+}
+
+func (p *exprParser) parseMul() (node, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *exprParser) parseUnary() (node, error) {
+	if t, ok := p.acceptOp("-", "+", "!"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: t.text, x: x, pos: t.pos}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return &numberNode{val: t.num}, nil
+	case tokIdent:
+		if p.peek().kind != tokLParen {
+			return &varNode{name: t.text, pos: t.pos}, nil
+		}
+		p.next()
+		call := &callNode{name: strings.ToUpper(t.text), pos: t.pos}
+		if p.peek().kind == tokRParen {
+			p.next()
+			return call, nil
+		}
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			sep := p.next()
+			if sep.kind == tokRParen {
+				return call, nil
+			}
+			if sep.kind != tokComma {
+				return nil, p.errorf(sep, "expected ',' or ')' in call to %s", call.name)
+			}
+		}
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "expected ')'")
+		}
+		return x, nil
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	default:
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+}
+
+// evalEnv is what expressions are evaluated against: script params, the
+// variables set while the script runs and the random state shared by RAND and
+// PREV_RAND.
+type evalEnv struct {
+	src      string
+	params   map[string]interface{}
+	vars     map[string]float64
+	prevRand float64
+	intn     func(n int) int
+	// onEval, if set, is called before every expression is evaluated and
+	// may stop the evaluation.
+	onEval func() error
+}
+
+func (env *evalEnv) set(name string, val float64) {
+	if env.vars == nil {
+		env.vars = map[string]float64{}
+	}
+	env.vars[name] = val
+}
+
+func (env *evalEnv) errorf(pos int, format string, args ...interface{}) error {
+	return &ExprError{Expr: env.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (env *evalEnv) fieldSize() int {
+	if v, ok := env.params["FIELD_SIZE"]; ok {
+		if f, ok := toFloat(v); ok {
+			return int(f)
+		}
+	}
+	return 10
+}
+
+func (e *Expr) eval(env *evalEnv) (float64, error) {
+	if env.onEval != nil {
+		if err := env.onEval(); err != nil {
+			return 0, err
+		}
+	}
+	env.src = e.src
+	return e.root.eval(env)
+}
+
+func (n *numberNode) eval(env *evalEnv) (float64, error) {
+	return n.val, nil
+}
+
+func (n *varNode) eval(env *evalEnv) (float64, error) {
+	if val, ok := env.vars[n.name]; ok {
+		return val, nil
+	}
+	if val, ok := env.params[n.name]; ok {
+		if f, ok := toFloat(val); ok {
+			return f, nil
+		}
+		if s, ok := val.(string); ok {
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, nil
+			}
+		}
+		return 0, env.errorf(n.pos, "variable %s is not a number", n.name)
+	}
+	if n.name == "FIELD_SIZE" {
+		return float64(env.fieldSize()), nil
+	}
+	return 0, env.errorf(n.pos, "unknown variable %s", n.name)
+}
+
+func (n *unaryNode) eval(env *evalEnv) (float64, error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "-":
+		return -x, nil
+	case "!":
+		return boolToFloat(x == 0), nil
+	}
+	return x, nil
+}
+
+func (n *binaryNode) eval(env *evalEnv) (float64, error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, env.errorf(n.pos, "division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, env.errorf(n.pos, "division by zero")
+		}
+		return math.Mod(l, r), nil
+	case "==":
+		return boolToFloat(l == r), nil
+	case "!=":
+		return boolToFloat(l != r), nil
+	case "<":
+		return boolToFloat(l < r), nil
+	case "<=":
+		return boolToFloat(l <= r), nil
+	case ">":
+		return boolToFloat(l > r), nil
+	case ">=":
+		return boolToFloat(l >= r), nil
+	case "&&":
+		return boolToFloat(l != 0 && r != 0), nil
+	case "||":
+		return boolToFloat(l != 0 || r != 0), nil
+	}
+	return 0, env.errorf(n.pos, "unknown operator %s", n.op)
+}
+
+func (n *callNode) eval(env *evalEnv) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	arity := func(min, max int) error {
+		if len(args) < min || len(args) > max {
+			if min == max {
+				return env.errorf(n.pos, "%s expects %d argument(s), got %d", n.name, min, len(args))
+			}
+			return env.errorf(n.pos, "%s expects %d to %d arguments, got %d", n.name, min, max, len(args))
+		}
+		return nil
+	}
+
+	switch n.name {
+	case "RAND":
+		if err := arity(0, 1); err != nil {
+			return 0, err
+		}
+		limit := env.fieldSize()
+		if len(args) == 1 {
+			limit = int(args[0])
+		}
+		if limit <= 0 {
+			return 0, env.errorf(n.pos, "RAND limit must be positive")
+		}
+		env.prevRand = float64(env.intn(limit))
+		return env.prevRand, nil
+	case "PREV_RAND":
+		if err := arity(0, 0); err != nil {
+			return 0, err
+		}
+		return env.prevRand, nil
+	case "MIN", "MAX":
+		if len(args) == 0 {
+			return 0, env.errorf(n.pos, "%s expects at least 1 argument", n.name)
+		}
+		res := args[0]
+		for _, a := range args[1:] {
+			if n.name == "MIN" {
+				res = math.Min(res, a)
+			} else {
+				res = math.Max(res, a)
+			}
+		}
+		return res, nil
+	case "ABS":
+		if err := arity(1, 1); err != nil {
+			return 0, err
+		}
+		return math.Abs(args[0]), nil
+	case "CLAMP":
+		if err := arity(3, 3); err != nil {
+			return 0, err
+		}
+		return math.Max(args[1], math.Min(args[2], args[0])), nil
+	}
+	return 0, env.errorf(n.pos, "unknown function %s", n.name)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// walk calls fn for every node of the expression tree.
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *unaryNode:
+		walk(n.x, fn)
+	case *binaryNode:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case *callNode:
+		for _, a := range n.args {
+			walk(a, fn)
+		}
+	}
+}
+
+// exprFunctions are the functions expressions may call.
+var exprFunctions = map[string]bool{
+	"RAND": true, "PREV_RAND": true, "MIN": true, "MAX": true, "ABS": true, "CLAMP": true,
+}
@@ -0,0 +1,98 @@
+package items
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseExpr_Eval(t *testing.T) {
+	params := map[string]interface{}{"x": 3, "y": 7.0, "FIELD_SIZE": 10}
+	cases := []struct {
+		expr string
+		want float64
+	}{
+		{"$x", 3},
+		{"x*2", 6},
+		{"-x + 1", -2},
+		{"(x + y) * 2", 20},
+		{"y % 4", 3},
+		{"y / 2", 3.5},
+		{"x < y", 1},
+		{"x == 3 && y != 7", 0},
+		{"!(x >= y) || 0", 1},
+		{"MIN(x, y, 5)", 3},
+		{"MAX(x, y)", 7},
+		{"ABS(x - y)", 4},
+		{"CLAMP(x - 10, 0, FIELD_SIZE - 1)", 0},
+		{"{'RAND':'None'} - {'PREV_RAND':'None'}", 0},
+		{`{"RAND":"None"} - FIELD_SIZE + $x`, 3 + 4 - 10},
+	}
+	for _, c := range cases {
+		e, err := ParseExpr(c.expr)
+		if err != nil {
+			t.Errorf("%q: parse error: %v", c.expr, err)
+			continue
+		}
+		env := &evalEnv{params: params, intn: func(n int) int { return 4 }}
+		got, err := e.eval(env)
+		if err != nil {
+			t.Errorf("%q: eval error: %v", c.expr, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%q = %v, want %v", c.expr, got, c.want)
+		}
+	}
+}
+
+func TestParseExpr_ErrorPositions(t *testing.T) {
+	cases := []struct {
+		expr string
+		pos  int
+	}{
+		{"x +", 4},
+		{"x + * 2", 5},
+		{"(x + 1", 7},
+		{"x # 1", 3},
+		{"MIN(x y)", 7},
+		{"", 1},
+		// Columns count runes, not bytes.
+		{"счёт # 1", 6},
+		{"счёт +", 7},
+		{"$счёт * ход)", 12},
+	}
+	for _, c := range cases {
+		_, err := ParseExpr(c.expr)
+		var exprErr *ExprError
+		if !errors.As(err, &exprErr) {
+			t.Errorf("%q: expected ExprError, got %v", c.expr, err)
+			continue
+		}
+		if exprErr.Pos != c.pos {
+			t.Errorf("%q: error at column %d, want %d (%v)", c.expr, exprErr.Pos, c.pos, err)
+		}
+	}
+
+	// Non-ASCII names are read whole, and a stray symbol is reported as is.
+	e, err := ParseExpr("счёт + 1")
+	if err != nil {
+		t.Errorf("non-ASCII name: %v", err)
+	} else if got, err := e.eval(&evalEnv{params: map[string]interface{}{"счёт": 2}}); err != nil || got != 3 {
+		t.Errorf("non-ASCII name = %v, %v", got, err)
+	}
+	if _, err := ParseExpr("x + €"); err == nil || !strings.Contains(err.Error(), "'€'") {
+		t.Errorf("stray symbol: got %v", err)
+	}
+
+	e, _ = ParseExpr("x + zz")
+	_, err = e.eval(&evalEnv{params: map[string]interface{}{"x": 1}})
+	var exprErr *ExprError
+	if !errors.As(err, &exprErr) || exprErr.Pos != 5 {
+		t.Errorf("unknown variable: got %v", err)
+	}
+	e, _ = ParseExpr("x / 0")
+	if _, err := e.eval(&evalEnv{params: map[string]interface{}{"x": 1}}); err == nil {
+		t.Error("expected division by zero error")
+	}
+}
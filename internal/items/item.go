@@ -1,44 +1,67 @@
 package items
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"lesta-battleship/server-core/internal/game"
-	"net/http"
+	"time"
+)
+
+// Item kinds, for display: defense items have an action on the user's own
+// board. Every action runs on the board its spec targets, whatever the kind.
+const (
+	KindAttack  = "attack"
+	KindDefense = "defense"
 )
 
 type Item struct {
 	Name        string `json:"name"`
 	Kind        string `json:"kind"`
 	Description string `json:"description"`
+	Input       string `json:"input,omitempty"`
 	Script      string `json:"script"`
 	ID          int    `json:"id"`
+
+	// Actions is the parsed script and Line the line the item starts on in its
+	// catalogue file, both set by LoadCatalogue.
+	Actions []Action `json:"-"`
+	Line    int      `json:"-"`
+	// Version identifies the catalogue the item was loaded with; compiled
+	// programs are cached by it.
+	Version string `json:"-"`
 }
 
+var defaultProvider = NewHTTPProvider(DefaultItemsURL, 5*time.Second)
+
+// GetItemsInfo fetches the catalogue from the items backend.
 func GetItemsInfo() ([]Item, error) {
-	r, err := http.Get("http://37.9.53.107/items/")
+	return defaultProvider.Items(context.Background())
+}
+
+func UseItem(id int, state *game.GameState, itemsList []Item, params map[string]interface{}) (*Result, error) {
+	return (&Runtime{}).UseItem(context.Background(), id, state, itemsList, params)
+}
+
+func (rt *Runtime) UseItem(ctx context.Context, id int, state *game.GameState, itemsList []Item, params map[string]interface{}) (*Result, error) {
+	item, err := FindItem(itemsList, id)
 	if err != nil {
 		return nil, err
 	}
-	defer r.Body.Close()
-
-	var items []Item
-	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+	cmd, err := rt.Command(ctx, item, params)
+	if err != nil {
 		return nil, err
 	}
-	return items, nil
+	if err := cmd.Execute(state); err != nil {
+		return nil, err
+	}
+	return &cmd.Result, nil
 }
 
-func UseItem(id int, state *game.GameState, itemsList []Item, params map[string]interface{}) (string, error) {
-	var item *Item
-	for i := range itemsList {
-		if itemsList[i].ID == id {
-			item = &itemsList[i]
-			break
+func FindItem(itemsList []Item, id int) (Item, error) {
+	for _, item := range itemsList {
+		if item.ID == id {
+			return item, nil
 		}
 	}
-	if item == nil {
-		return "", fmt.Errorf("item with id %d not found", id)
-	}
-	return RunScript(item.Script, state, params)
+	return Item{}, fmt.Errorf("item with id %d not found", id)
 }
@@ -9,6 +9,14 @@ import (
 	"time"
 )
 
+func evalExpr(expr string, params map[string]interface{}, prevRand float64) (interface{}, error) {
+	e, err := ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.eval(&evalEnv{params: params, prevRand: prevRand, intn: rand.Intn})
+}
+
 func printField(field [10][10]game.CellState) {
 	for y := 0; y < 10; y++ {
 		for x := 0; x < 10; x++ {
@@ -0,0 +1,113 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Limits bound a single script run. Zero fields take the value from
+// DefaultLimits, so every run is bounded.
+type Limits struct {
+	MaxActions     int           // actions and loop iterations executed
+	MaxEvaluations int           // expressions evaluated
+	MaxMutations   int           // commands applied to the board
+	Timeout        time.Duration // wall-clock budget
+}
+
+var DefaultLimits = Limits{
+	MaxActions:     1000,
+	MaxEvaluations: 5000,
+	MaxMutations:   200,
+	Timeout:        100 * time.Millisecond,
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.MaxActions <= 0 {
+		l.MaxActions = DefaultLimits.MaxActions
+	}
+	if l.MaxEvaluations <= 0 {
+		l.MaxEvaluations = DefaultLimits.MaxEvaluations
+	}
+	if l.MaxMutations <= 0 {
+		l.MaxMutations = DefaultLimits.MaxMutations
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultLimits.Timeout
+	}
+	return l
+}
+
+// Names of the limits reported in LimitError.
+const (
+	LimitActions     = "actions"
+	LimitEvaluations = "evaluations"
+	LimitMutations   = "mutations"
+	LimitTime        = "time"
+)
+
+// LimitError is returned when a script run exceeds one of its Limits. The
+// script is rolled back before it is returned.
+type LimitError struct {
+	Limit string
+	// Max is the exceeded limit; for LimitTime it is a time.Duration.
+	Max int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == LimitTime {
+		return fmt.Sprintf("script exceeded its time budget of %v", time.Duration(e.Max))
+	}
+	return fmt.Sprintf("script exceeded the limit of %d %s", e.Max, e.Limit)
+}
+
+// Unwrap lets errors.Is(err, context.DeadlineExceeded) match a time limit.
+func (e *LimitError) Unwrap() error {
+	if e.Limit == LimitTime {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// budget counts what a script run uses against its limits.
+type budget struct {
+	ctx    context.Context
+	limits Limits
+
+	actions, evaluations, mutations int
+}
+
+func (b *budget) check() error {
+	if err := b.ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &LimitError{Limit: LimitTime, Max: int64(b.limits.Timeout)}
+		}
+		return fmt.Errorf("script cancelled: %w", err)
+	}
+	return nil
+}
+
+func (b *budget) action() error {
+	b.actions++
+	if b.actions > b.limits.MaxActions {
+		return &LimitError{Limit: LimitActions, Max: int64(b.limits.MaxActions)}
+	}
+	return b.check()
+}
+
+func (b *budget) evaluation() error {
+	b.evaluations++
+	if b.evaluations > b.limits.MaxEvaluations {
+		return &LimitError{Limit: LimitEvaluations, Max: int64(b.limits.MaxEvaluations)}
+	}
+	return nil
+}
+
+func (b *budget) mutation() error {
+	b.mutations++
+	if b.mutations > b.limits.MaxMutations {
+		return &LimitError{Limit: LimitMutations, Max: int64(b.limits.MaxMutations)}
+	}
+	return b.check()
+}
@@ -0,0 +1,87 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		script string
+		want   string
+	}{
+		{
+			name:   "actions",
+			limits: Limits{MaxActions: 3},
+			script: `[{"LET": {"a": "1"}}, {"LET": {"a": "2"}}, {"OPEN_CELL": {"x": "a", "y": "a"}}, {"END_PLAYER_ACTION": "None"}]`,
+			want:   LimitActions,
+		},
+		{
+			name:   "evaluations",
+			limits: Limits{MaxEvaluations: 5},
+			script: `[{"OPEN_CELL": {"x": "1", "y": "1"}}, {"OPEN_CELL": {"x": "2", "y": "2"}}, {"OPEN_CELL": {"x": "3", "y": "3"}}]`,
+			want:   LimitEvaluations,
+		},
+		{
+			name:   "mutations",
+			limits: Limits{MaxMutations: 2},
+			script: `[{"FOR": {"var": "i", "from": "0", "to": "2", "do": {"MAKE_SHOT": {"x": "i", "y": "0"}}}}]`,
+			want:   LimitMutations,
+		},
+		{
+			name:   "time",
+			limits: Limits{Timeout: time.Nanosecond},
+			script: `[{"REPEAT": {"times": "100", "do": {"LET": {"a": "1"}}}}]`,
+			want:   LimitTime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := game.NewGameState()
+			rt := &Runtime{Limits: tt.limits}
+			_, err := rt.RunScript(context.Background(), tt.script, state, nil)
+
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) || limitErr.Limit != tt.want {
+				t.Fatalf("got %v, want the %s limit", err, tt.want)
+			}
+			if state.Field != game.NewGameState().Field || len(state.ShotsMade) != 0 {
+				t.Error("state not rolled back")
+			}
+		})
+	}
+}
+
+func TestLimits_WithinBudget(t *testing.T) {
+	rt := &Runtime{Limits: Limits{MaxActions: 4, MaxEvaluations: 6, MaxMutations: 3}}
+	script := `[{"OPEN_CELL": {"x": "1", "y": "1"}}, {"OPEN_CELL": {"x": "2", "y": "2"}}, {"OPEN_CELL": {"x": "3", "y": "3"}}, {"END_PLAYER_ACTION": "None"}]`
+	if _, err := rt.RunScript(context.Background(), script, game.NewGameState(), nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimits_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	state := game.NewGameState()
+	_, err := RunScript(ctx, `[{"OPEN_CELL": {"x": "1", "y": "1"}}]`, state, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if state.Field[1][1] != game.Empty {
+		t.Error("cancelled script changed the board")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = RunScript(ctx, `[{"OPEN_CELL": {"x": "1", "y": "1"}}]`, game.NewGameState(), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want a deadline error", err)
+	}
+}
@@ -0,0 +1,103 @@
+package items
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// memberOffsets returns the byte offsets, relative to raw, of the member
+// values of the JSON object or array at the start of raw. For objects the
+// keys are returned as well.
+func memberOffsets(raw []byte) ([]int, []string, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok || (delim != '{' && delim != '[') {
+		return nil, nil, errors.New("expected object or array")
+	}
+	var offsets []int
+	var keys []string
+	for dec.More() {
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			keys = append(keys, key.(string))
+		}
+		offsets = append(offsets, skipSeparators(raw, int(dec.InputOffset())))
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return nil, nil, err
+		}
+	}
+	return offsets, keys, nil
+}
+
+func skipSeparators(raw []byte, i int) int {
+	for i < len(raw) {
+		switch raw[i] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func lineAt(src []byte, offset int) int {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	return bytes.Count(src[:offset], []byte{'\n'}) + 1
+}
+
+// setActionLines records in every action of the array at src[offset:] the
+// line it starts on, including actions nested in branches.
+func setActionLines(actions []Action, src []byte, offset int) {
+	offsets, _, err := memberOffsets(src[offset:])
+	if err != nil {
+		return
+	}
+	for i := range actions {
+		if i < len(offsets) {
+			setLines(&actions[i], src, offset+offsets[i])
+		}
+	}
+}
+
+func setLines(a *Action, src []byte, offset int) {
+	a.Line = lineAt(src, offset)
+	if a.Branches == nil {
+		return
+	}
+	// The branches live under the function key or under "Args".
+	offsets, keys, err := memberOffsets(src[offset:])
+	if err != nil {
+		return
+	}
+	for i, key := range keys {
+		if key != a.Name && key != "Args" {
+			continue
+		}
+		branchStart := offset + offsets[i]
+		branchOffsets, branchKeys, err := memberOffsets(src[branchStart:])
+		if err != nil {
+			return
+		}
+		for j, branchKey := range branchKeys {
+			branch := a.Branches[branchKey]
+			at := branchStart + branchOffsets[j]
+			if at < len(src) && src[at] == '[' {
+				setActionLines(branch, src, at)
+			} else if len(branch) == 1 {
+				setLines(&branch[0], src, at)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package items
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Provider supplies the item catalogue.
+type Provider interface {
+	Items(ctx context.Context) ([]Item, error)
+}
+
+// HTTPProvider fetches the catalogue from the items backend. It sends the
+// ETag of the last response and keeps serving the cached items on 304, and
+// also when the backend is unreachable once something has been fetched.
+type HTTPProvider struct {
+	URL     string
+	Timeout time.Duration
+	Client  *http.Client
+
+	mu    sync.Mutex
+	etag  string
+	items []Item
+}
+
+const DefaultItemsURL = "http://37.9.53.107/items/"
+
+func NewHTTPProvider(url string, timeout time.Duration) *HTTPProvider {
+	return &HTTPProvider{URL: url, Timeout: timeout}
+}
+
+func (p *HTTPProvider) Items(ctx context.Context) ([]Item, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock guards only the cached state, never the request itself, so a
+	// slow backend does not queue up every caller behind it.
+	etag, cached := p.cached()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	r, err := client.Do(req)
+	if err != nil {
+		if cached != nil {
+			log.Println("[ITEMS] Backend unavailable, serving cached catalogue:", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	switch r.StatusCode {
+	case http.StatusNotModified:
+		return cached, nil
+	case http.StatusOK:
+	default:
+		if cached != nil {
+			log.Println("[ITEMS] Backend returned", r.Status, "serving cached catalogue")
+			return cached, nil
+		}
+		return nil, fmt.Errorf("items backend returned %s", r.Status)
+	}
+
+	var items []Item
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		if cached != nil {
+			log.Println("[ITEMS] Bad catalogue from backend, serving cached catalogue:", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+	p.mu.Lock()
+	p.items = items
+	p.etag = r.Header.Get("ETag")
+	p.mu.Unlock()
+	return items, nil
+}
+
+func (p *HTTPProvider) cached() (string, []Item) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.etag, p.items
+}
+
+// FileProvider reads a catalogue file in the Items_logic2.json format.
+type FileProvider struct {
+	Path string
+}
+
+func (p FileProvider) Items(ctx context.Context) ([]Item, error) {
+	c, err := LoadCatalogueFile(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	return c.Items, nil
+}
+
+// MemoryProvider serves a fixed list of items, for tests.
+type MemoryProvider []Item
+
+func (p MemoryProvider) Items(ctx context.Context) ([]Item, error) {
+	return []Item(p), nil
+}
+
+// Cache keeps the last catalogue loaded from a provider, so that lookups
+// never wait for the network, and can reload it periodically.
+type Cache struct {
+	provider Provider
+
+	mu    sync.RWMutex
+	items []Item
+}
+
+func NewCache(p Provider) *Cache {
+	return &Cache{provider: p}
+}
+
+// Reload fetches the catalogue from the provider and stamps it with its
+// version. On failure the previous catalogue stays in place. An ID that now
+// names a different item is logged, since inventories hold items by ID.
+func (c *Cache) Reload(ctx context.Context) error {
+	fetched, err := c.provider.Items(ctx)
+	if err != nil {
+		return err
+	}
+	items := append([]Item(nil), fetched...)
+	stampVersion(items)
+	c.mu.Lock()
+	for _, moved := range remapped(c.items, items) {
+		log.Printf("[ITEMS] Item %d was %q and is now %q", moved.ID, moved.From, moved.To)
+	}
+	c.items = items
+	c.mu.Unlock()
+	return nil
+}
+
+// remap is an item ID that names a different item after a reload.
+type remap struct {
+	ID       int
+	From, To string
+}
+
+func remapped(old, items []Item) []remap {
+	names := make(map[int]string, len(old))
+	for _, it := range old {
+		names[it.ID] = it.Name
+	}
+	var moved []remap
+	for _, it := range items {
+		if name, ok := names[it.ID]; ok && name != it.Name {
+			moved = append(moved, remap{ID: it.ID, From: name, To: it.Name})
+		}
+	}
+	return moved
+}
+
+func (c *Cache) Items() []Item {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.items
+}
+
+// Watch reloads the catalogue every interval until ctx is done.
+func (c *Cache) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Reload(ctx); err != nil {
+				log.Println("[ITEMS] Catalogue reload failed:", err)
+			}
+		}
+	}
+}
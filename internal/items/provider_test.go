@@ -0,0 +1,126 @@
+package items
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPProvider_ETagAndFallback(t *testing.T) {
+	requests, notModified := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		json.NewEncoder(w).Encode([]Item{{ID: 1, Name: "Крест Нахимова"}})
+	}))
+
+	p := NewHTTPProvider(srv.URL, time.Second)
+	for i := 0; i < 2; i++ {
+		got, err := p.Items(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != 1 {
+			t.Fatalf("request %d: got %+v", i, got)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("requests=%d notModified=%d", requests, notModified)
+	}
+
+	srv.Close()
+	got, err := p.Items(context.Background())
+	if err != nil || len(got) != 1 {
+		t.Errorf("expected cached items when the backend is down, got %v, %v", got, err)
+	}
+}
+
+func TestHTTPProvider_BadBodyServesCache(t *testing.T) {
+	broken := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken {
+			w.Write([]byte("{not json"))
+			return
+		}
+		json.NewEncoder(w).Encode([]Item{{ID: 1, Name: "Крест Нахимова"}})
+	}))
+	defer srv.Close()
+
+	p := NewHTTPProvider(srv.URL, time.Second)
+	if _, err := p.Items(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	broken = true
+	got, err := p.Items(context.Background())
+	if err != nil || len(got) != 1 {
+		t.Errorf("expected cached items on a bad body, got %v, %v", got, err)
+	}
+
+	if _, err := NewHTTPProvider(srv.URL, time.Second).Items(context.Background()); err == nil {
+		t.Error("expected an error on a bad body with nothing cached")
+	}
+}
+
+func TestHTTPProvider_FetchDoesNotHoldLock(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode([]Item{{ID: 1}})
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	p := NewHTTPProvider(srv.URL, 5*time.Second)
+	go p.Items(context.Background())
+	time.Sleep(50 * time.Millisecond) // let the first fetch reach the server
+
+	locked := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		p.mu.Unlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("the provider lock is held during the request")
+	}
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Items(ctx context.Context) ([]Item, error) {
+	return nil, errors.New("backend down")
+}
+
+func TestCache_Reload(t *testing.T) {
+	c := NewCache(MemoryProvider{{ID: 7}})
+	if err := c.Reload(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	c.provider = failingProvider{}
+	if err := c.Reload(context.Background()); err == nil {
+		t.Error("expected reload error")
+	}
+	if got := c.Items(); len(got) != 1 || got[0].ID != 7 {
+		t.Errorf("failed reload replaced the catalogue: %v", got)
+	}
+
+	if moved := remapped(c.Items(), []Item{{ID: 7, Name: "other"}, {ID: 8}}); len(moved) != 1 || moved[0] != (remap{ID: 7, To: "other"}) {
+		t.Errorf("remapped = %+v", moved)
+	}
+
+	file := NewCache(FileProvider{Path: "Items_logic2.json"})
+	if err := file.Reload(context.Background()); err != nil || len(file.Items()) != 7 {
+		t.Errorf("file provider: %d items, %v", len(file.Items()), err)
+	}
+}
@@ -0,0 +1,212 @@
+package items
+
+import (
+	"context"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/rng"
+	"testing"
+)
+
+// queryState has a cruiser at (2,2)-(4,2) hit in the middle and a submarine
+// at (7,7).
+func queryState() *game.GameState {
+	state := game.NewGameState()
+	state.Ships["c"] = game.Ship{ID: "c", Type: game.Cruiser, Coords: []game.Coord{{X: 2, Y: 2}, {X: 3, Y: 2}, {X: 4, Y: 2}}}
+	state.Ships["s"] = game.Ship{ID: "s", Type: game.Submarine, Coords: []game.Coord{{X: 7, Y: 7}}}
+	state.Field[2][2] = game.ShipCell
+	state.Field[3][2] = game.Hit
+	state.Field[4][2] = game.ShipCell
+	state.Field[7][7] = game.ShipCell
+	return state
+}
+
+func TestQueries(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		outcome string
+		value   int
+	}{
+		{"count row", `[{"COUNT_SHIP_CELLS": {"x": "0", "y": "2", "x2": "FIELD_SIZE-1", "y2": "2"}}]`, OutcomeQueried, 2},
+		{"count column", `[{"COUNT_SHIP_CELLS": {"x": "7", "y": "0", "x2": "7", "y2": "9"}}]`, OutcomeQueried, 1},
+		{"count area", `[{"COUNT_SHIP_CELLS": {"x": "9", "y": "9", "x2": "3", "y2": "0"}}]`, OutcomeQueried, 2},
+		{"count clipped", `[{"COUNT_SHIP_CELLS": {"x": "-5", "y": "-5", "x2": "20", "y2": "20"}}]`, OutcomeQueried, 3},
+		{"is ship", `[{"IS_SHIP": {"x": "2", "y": "2"}}]`, OutcomeQueried, 1},
+		{"is ship on hit cell", `[{"IS_SHIP": {"x": "3", "y": "2"}}]`, OutcomeQueried, 1},
+		{"is water", `[{"IS_SHIP": {"x": "2", "y": "3"}}]`, OutcomeQueried, 0},
+		{"is ship out of bounds", `[{"IS_SHIP": {"x": "10", "y": "2"}}]`, OutcomeQueried, 0},
+		{"distance", `[{"DISTANCE_TO_NEAREST_SHIP": {"x": "5", "y": "5"}}]`, OutcomeQueried, 4},
+		{"distance ignores hit cells", `[{"DISTANCE_TO_NEAREST_SHIP": {"x": "3", "y": "2"}}]`, OutcomeQueried, 1},
+		{"distance on ship", `[{"DISTANCE_TO_NEAREST_SHIP": {"x": "7", "y": "7"}}]`, OutcomeQueried, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := queryState()
+			before := state.Clone()
+			res, err := RunScript(context.Background(), tt.script, state, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := res.Effects[0]
+			if e.Outcome != tt.outcome || e.Value == nil || *e.Value != tt.value {
+				t.Errorf("effect = %+v (value %v), want %s %d", e, e.Value, tt.outcome, tt.value)
+			}
+			if state.Field != before.Field {
+				t.Error("query changed the field")
+			}
+		})
+	}
+}
+
+func TestQueries_NoShipsLeft(t *testing.T) {
+	res, err := RunScript(context.Background(), `[{"DISTANCE_TO_NEAREST_SHIP": {"x": "0", "y": "0"}}]`, game.NewGameState(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := res.Effects[0].Value; v == nil || *v != -1 {
+		t.Errorf("distance on an empty board = %v, want -1", v)
+	}
+}
+
+func TestQueries_ResultVariable(t *testing.T) {
+	state := queryState()
+	// Shoot at the cell right of the nearest ship, found through RESULT.
+	script := `[
+		{"DISTANCE_TO_NEAREST_SHIP": {"x": "4", "y": "5"}},
+		{"MAKE_SHOT": {"x": "4", "y": "5-RESULT"}}
+	]`
+	res, err := RunScript(context.Background(), script, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Last() != OutcomeHit || state.Field[4][2] != game.Hit {
+		t.Errorf("shot at RESULT missed: %+v", res.Effects)
+	}
+}
+
+func TestRevealShip(t *testing.T) {
+	tests := []struct {
+		name    string
+		x, y    int
+		outcome string
+		cells   int
+	}{
+		{"hit cell", 3, 2, OutcomeRevealedShip, 3},
+		{"intact cell", 2, 2, OutcomeNoShip, 1},
+		{"water", 0, 0, OutcomeNoShip, 1},
+		{"out of bounds", -1, 0, OutcomeNoShip, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := RunScript(context.Background(), `[{"REVEAL_SHIP": {}}]`, queryState(), map[string]interface{}{"x": tt.x, "y": tt.y})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if e := res.Effects[0]; e.Outcome != tt.outcome || len(e.Cells) != tt.cells {
+				t.Errorf("effect = %+v", e)
+			}
+		})
+	}
+}
+
+func TestRandomEmptyCell(t *testing.T) {
+	state := queryState()
+	rt := &Runtime{RNG: rng.New(3)}
+	res, err := rt.RunScript(context.Background(), `[
+		{"RANDOM_EMPTY_CELL": "None"},
+		{"OPEN_CELL": {"x": "RESULT_X", "y": "RESULT_Y"}}
+	]`, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	picked := res.Effects[0].Cells
+	if res.Effects[0].Outcome != OutcomeQueried || len(picked) != 1 {
+		t.Fatalf("effect = %+v", res.Effects[0])
+	}
+	if res.Effects[1].Outcome != OutcomeRevealedEmpty || res.Effects[1].Cells[0] != picked[0] {
+		t.Errorf("opened %+v, want the picked cell %v", res.Effects[1], picked[0])
+	}
+
+	full := game.NewGameState()
+	for x := range full.Field {
+		for y := range full.Field[x] {
+			full.Field[x][y] = game.Miss
+		}
+	}
+	res, err = RunScript(context.Background(), `[{"RANDOM_EMPTY_CELL": "None"}]`, full, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Last() != OutcomeNoCell {
+		t.Errorf("outcome on a full board = %q", res.Last())
+	}
+}
+
+func TestRepairCell(t *testing.T) {
+	state := queryState()
+	res, err := RunScript(context.Background(), `[{"REPAIR_CELL": {"x": "3", "y": "2"}}]`, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Last() != OutcomeRepaired || state.Field[3][2] != game.ShipCell {
+		t.Errorf("repair failed: %+v, cell %v", res.Effects, state.Field[3][2])
+	}
+
+	for _, script := range []string{
+		`[{"REPAIR_CELL": {"x": "2", "y": "2"}}]`,  // intact
+		`[{"REPAIR_CELL": {"x": "0", "y": "0"}}]`,  // water
+		`[{"REPAIR_CELL": {"x": "10", "y": "0"}}]`, // out of bounds
+	} {
+		if _, err := RunScript(context.Background(), script, queryState(), nil); err == nil {
+			t.Errorf("expected %s to fail", script)
+		}
+	}
+
+	state = queryState()
+	if _, err := RunScript(context.Background(), `[{"REPAIR_CELL": {"x": "3", "y": "2"}}, {"MAKE_SHOT": {"x": "10", "y": "0"}}]`, state, nil); err == nil {
+		t.Fatal("expected the shot to fail")
+	}
+	if state.Field[3][2] != game.Hit {
+		t.Error("repair was not rolled back")
+	}
+}
+
+func TestQueries_DeclaredInCatalogue(t *testing.T) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, decl := range BuiltinFunctions {
+		got, ok := catalogue.Functions[name]
+		if !ok {
+			t.Errorf("%s is not declared in the catalogue", name)
+			continue
+		}
+		if len(got.Input) != len(decl.Input) {
+			t.Errorf("%s: catalogue input %v, runtime %v", name, got.Input, decl.Input)
+		}
+	}
+	for name := range BuiltinVariables {
+		if _, ok := catalogue.Variables[name]; !ok {
+			t.Errorf("variable %s is not declared in the catalogue", name)
+		}
+	}
+
+	item := Item{Name: "Радар", Actions: mustParse(t, `[
+		{"COUNT_SHIP_CELLS": {"x": "0", "x2": "9", "y2": "y"}},
+		{"IS_SHIP": {}},
+		{"SWITCH_CASE": {"1": {"REVEAL_SHIP": {}}}}
+	]`)}
+	if diags := Validate(item); HasErrors(diags) {
+		t.Errorf("unexpected errors: %v", diags)
+	}
+}
+
+func mustParse(t *testing.T, script string) []Action {
+	t.Helper()
+	actions, err := ParseScript(script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return actions
+}
@@ -0,0 +1,253 @@
+package items
+
+import (
+	"errors"
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/transaction"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ArgType is how an action argument is written in a script.
+type ArgType int
+
+const (
+	// ArgNumber is an expression such as "x+1" or {"RAND": "None"}.
+	ArgNumber ArgType = iota
+	// ArgString is a literal from the spec's Values, or "$param".
+	ArgString
+)
+
+type ArgSpec struct {
+	Name   string
+	Type   ArgType
+	Values []string // allowed literals of an ArgString, if restricted
+	Label  string   // what the values are, for diagnostics; defaults to Name
+}
+
+// Target is the board an action works on. Each action of a script runs on
+// its own target, so one item may act on both boards.
+type Target int
+
+const (
+	TargetEnemy Target = iota
+	TargetOwn
+)
+
+// Board is the board of a room the target names, seen from the item's user.
+func (t Target) Board() transaction.Board {
+	if t == TargetOwn {
+		return transaction.OwnBoard
+	}
+	return transaction.EnemyBoard
+}
+
+// Action groups, for game modes to enable or disable together.
+const (
+	GroupCore    = "core"
+	GroupEffects = "effects"
+	GroupQueries = "queries"
+)
+
+type ActionSpec struct {
+	Args   []ArgSpec
+	Target Target
+	Group  string
+}
+
+func (s ActionSpec) arg(name string) (ArgSpec, bool) {
+	for _, a := range s.Args {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return ArgSpec{}, false
+}
+
+func (s ActionSpec) inputs() []string {
+	var names []string
+	for _, a := range s.Args {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+// ActionHandler executes a registered action. Arguments missing from the
+// script are taken from the script param of the same name, when there is one.
+type ActionHandler func(c *Call) error
+
+type registeredAction struct {
+	spec    ActionSpec
+	handler ActionHandler
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]registeredAction{}
+	modes      = map[string]ActionSet{}
+)
+
+// Register adds an action item scripts can call. It is meant to be called
+// from init: programs compiled before the registration do not see it. It
+// panics if the name is taken or reserved for the script language.
+func Register(name string, spec ActionSpec, h ActionHandler) {
+	name = strings.ToUpper(name)
+	if name == "" || h == nil {
+		panic("items: Register needs a name and a handler")
+	}
+	if isSwitch(name) || isControl(name) || exprFunctions[name] {
+		panic("items: " + name + " is reserved")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := registry[name]; dup {
+		panic("items: Register called twice for " + name)
+	}
+	registry[name] = registeredAction{spec: spec, handler: h}
+	BuiltinFunctions[name] = FunctionDecl{Input: spec.inputs()}
+}
+
+// unregister removes an action, for tests.
+func unregister(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, name)
+	delete(BuiltinFunctions, name)
+}
+
+func lookupAction(name string) (registeredAction, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	a, ok := registry[strings.ToUpper(name)]
+	return a, ok
+}
+
+// LookupAction returns the spec of a registered action.
+func LookupAction(name string) (ActionSpec, bool) {
+	a, ok := lookupAction(name)
+	return a.spec, ok
+}
+
+// RegisteredActions lists the registered action names in order.
+func RegisteredActions() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var ErrActionDisabled = errors.New("action is disabled in this game mode")
+
+// ActionSet selects registered actions by name or group. The zero value
+// allows every action.
+type ActionSet struct {
+	Enable  []string // if set, only these actions and groups are allowed
+	Disable []string // removed from the enabled ones
+}
+
+func (s ActionSet) Allows(name string) bool {
+	name = strings.ToUpper(name)
+	spec, _ := LookupAction(name)
+	match := func(list []string) bool {
+		for _, e := range list {
+			if strings.ToUpper(e) == name || (spec.Group != "" && e == spec.Group) {
+				return true
+			}
+		}
+		return false
+	}
+	return (len(s.Enable) == 0 || match(s.Enable)) && !match(s.Disable)
+}
+
+func (s ActionSet) check(name string) error {
+	if !s.Allows(name) {
+		return fmt.Errorf("%w: %s", ErrActionDisabled, name)
+	}
+	return nil
+}
+
+// SetModeActions sets the actions item scripts may use in a game mode.
+func SetModeActions(mode string, set ActionSet) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	modes[mode] = set
+}
+
+// ModeActions returns the actions of a game mode; modes never set allow
+// every action.
+func ModeActions(mode string) ActionSet {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return modes[mode]
+}
+
+// Call is one execution of a registered action.
+type Call struct {
+	Name  string
+	Args  map[string]interface{}
+	State *game.GameState // the board the action targets
+
+	board transaction.Board
+	run   *scriptRun
+}
+
+func (c *Call) Number(key string) (float64, error) {
+	f, ok := toFloat(c.Args[key])
+	if !ok {
+		return 0, fmt.Errorf("invalid args for %s", c.Name)
+	}
+	return f, nil
+}
+
+func (c *Call) String(key string) (string, error) {
+	s, ok := c.Args[key].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid args for %s", c.Name)
+	}
+	return s, nil
+}
+
+func (c *Call) Coord(xKey, yKey string) (game.Coord, error) {
+	x, okX := toFloat(c.Args[xKey])
+	y, okY := toFloat(c.Args[yKey])
+	if !okX || !okY {
+		return game.Coord{}, fmt.Errorf("invalid args for %s", c.Name)
+	}
+	return game.Coord{X: int(x), Y: int(y)}, nil
+}
+
+// Apply applies cmd to the board; it is undone with the rest of the script.
+func (c *Call) Apply(cmd transaction.Command) error {
+	return c.run.apply(c.board, cmd)
+}
+
+// Effect records what the action did.
+func (c *Call) Effect(outcome string, cells ...game.Coord) *Effect {
+	return c.run.effect(c.Name, c.board, c.Args, outcome, cells...)
+}
+
+// Result records the value of a query and exposes it to the script as RESULT.
+func (c *Call) Result(val int, cells ...game.Coord) {
+	c.run.result(c.Name, c.board, c.Args, val, cells...)
+}
+
+// Set assigns a script variable.
+func (c *Call) Set(name string, val float64) {
+	c.run.env.set(name, val)
+}
+
+// Intn draws from the script's RNG, so the draw is recorded and replayed.
+func (c *Call) Intn(n int) int {
+	return c.run.env.intn(n)
+}
+
+func (c *Call) EndTurn() {
+	c.run.cmd.Result.EndsTurn = true
+}
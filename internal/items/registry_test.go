@@ -0,0 +1,117 @@
+package items
+
+import (
+	"context"
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"strings"
+	"testing"
+)
+
+func TestRegister_CustomAction(t *testing.T) {
+	Register("flood_row", ActionSpec{Args: []ArgSpec{{Name: "y"}}, Target: TargetOwn}, func(c *Call) error {
+		y, err := c.Number("y")
+		if err != nil {
+			return err
+		}
+		var cells []game.Coord
+		for x := 0; x < 10; x++ {
+			cell := game.Coord{X: x, Y: int(y)}
+			if err := c.Apply(&game.SetCellStatusCommand{Target: cell, Status: game.Miss}); err != nil {
+				return err
+			}
+			cells = append(cells, cell)
+		}
+		c.Effect(OutcomeStatusSet, cells...)
+		return nil
+	})
+	t.Cleanup(func() { unregister("FLOOD_ROW") })
+
+	state := game.NewGameState()
+	res, err := RunScript(context.Background(), `[{"FLOOD_ROW": {"y": "y+1"}}]`, state, map[string]interface{}{"y": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Field[9][3] != game.Miss || len(res.Effects[0].Cells) != 10 {
+		t.Errorf("row 3 not flooded: %v", res.Effects)
+	}
+
+	actions := mustParse(t, `[{"FLOOD_ROW": {"y": "1", "z": "2"}}]`)
+	if got := inferKind(actions); got != KindDefense {
+		t.Errorf("kind = %s, want %s from the target board", got, KindDefense)
+	}
+	diags := Validate(Item{Name: "flood", Actions: actions})
+	if len(diags) != 1 || !strings.Contains(diags[0].Msg, "unexpected argument z") {
+		t.Errorf("diagnostics = %v", diags)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering FLOOD_ROW twice did not panic")
+		}
+	}()
+	Register("FLOOD_ROW", ActionSpec{}, func(c *Call) error { return nil })
+}
+
+func TestRegister_Reserved(t *testing.T) {
+	for _, name := range []string{"IF", "SWITCH_CASE", "RAND"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %s did not panic", name)
+				}
+			}()
+			Register(name, ActionSpec{}, func(c *Call) error { return nil })
+		}()
+	}
+}
+
+func TestActionSet(t *testing.T) {
+	set := ActionSet{Enable: []string{GroupCore, "IS_SHIP"}, Disable: []string{"MAKE_SHOT"}}
+	for name, want := range map[string]bool{
+		"OPEN_CELL":   true,
+		"IS_SHIP":     true,
+		"MAKE_SHOT":   false,
+		"ADD_SHIELD":  false,
+		"REVEAL_SHIP": false,
+	} {
+		if got := set.Allows(name); got != want {
+			t.Errorf("Allows(%s) = %v, want %v", name, got, want)
+		}
+	}
+	if !(ActionSet{}).Allows("ADD_SONAR") {
+		t.Error("the zero set must allow every action")
+	}
+}
+
+func TestModeActions(t *testing.T) {
+	SetModeActions("classic", ActionSet{Disable: []string{GroupEffects}})
+	t.Cleanup(func() { SetModeActions("classic", ActionSet{}) })
+
+	rt := &Runtime{Actions: ModeActions("classic")}
+	state := game.NewGameState()
+	state.Ships["s"] = game.Ship{ID: "s", Type: game.Submarine, Coords: []game.Coord{{X: 1, Y: 1}}}
+	state.Field[1][1] = game.ShipCell
+	_, err := rt.RunScript(context.Background(), `[{"OPEN_CELL": {"x": "0", "y": "0"}}, {"ADD_SHIELD": {"x": "1", "y": "1", "turns": "2"}}]`, state, nil)
+	if !errors.Is(err, ErrActionDisabled) {
+		t.Fatalf("err = %v, want ErrActionDisabled", err)
+	}
+	if state.Field[0][0] != game.Empty || len(state.Effects) != 0 {
+		t.Error("a disabled script touched the board")
+	}
+
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetModeActions("classic", ActionSet{Disable: []string{"OPEN_CELL"}})
+	var disabled int
+	for _, d := range catalogue.ValidateMode("classic") {
+		if strings.Contains(d.Msg, "OPEN_CELL is disabled") {
+			disabled++
+		}
+	}
+	if disabled == 0 {
+		t.Error("ValidateMode did not report OPEN_CELL")
+	}
+}
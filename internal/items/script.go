@@ -0,0 +1,248 @@
+package items
+
+import (
+	"context"
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/rng"
+	"lesta-battleship/server-core/internal/transaction"
+)
+
+// RNG is the random source RAND draws from.
+type RNG interface {
+	Intn(n int) int
+}
+
+// Runtime holds what item scripts run with. The zero value is usable and
+// draws from a clock-seeded source; rooms inject their own recorded RNG so
+// every item use can be replayed.
+type Runtime struct {
+	RNG    RNG
+	Limits Limits
+	// Trace, if set, records every run of the runtime, replacing what it
+	// held before.
+	Trace *Trace
+	// Programs caches compiled items; nil means the package-wide Programs.
+	Programs *ProgramCache
+	// Actions are the registered actions scripts may use, usually those of
+	// the game mode.
+	Actions ActionSet
+}
+
+func (rt *Runtime) rng() RNG {
+	if rt.RNG == nil {
+		return rng.NewRandom()
+	}
+	return rt.RNG
+}
+
+func RunScript(ctx context.Context, script string, state *game.GameState, params map[string]interface{}) (*Result, error) {
+	return (&Runtime{}).RunScript(ctx, script, state, params)
+}
+
+func RunActions(ctx context.Context, actions []Action, state *game.GameState, params map[string]interface{}) (*Result, error) {
+	return (&Runtime{}).RunActions(ctx, actions, state, params)
+}
+
+func (rt *Runtime) RunScript(ctx context.Context, script string, state *game.GameState, params map[string]interface{}) (*Result, error) {
+	actions, err := ParseScript(script)
+	if err != nil {
+		return nil, err
+	}
+	return rt.RunActions(ctx, actions, state, params)
+}
+
+// RunActions runs the actions inside one transaction, so a failing script
+// leaves the state exactly as it was. The run stops with a *LimitError once
+// it exceeds the runtime's Limits, or when ctx is done.
+func (rt *Runtime) RunActions(ctx context.Context, actions []Action, state *game.GameState, params map[string]interface{}) (*Result, error) {
+	program, err := Compile(actions)
+	if err != nil {
+		return nil, err
+	}
+	return rt.Run(ctx, program, state, params)
+}
+
+// Run runs the program on a single board, which stands in for both the
+// user's and the enemy's.
+func (rt *Runtime) Run(ctx context.Context, program *Program, state *game.GameState, params map[string]interface{}) (*Result, error) {
+	cmd := rt.newCommand(ctx, program, params)
+	if err := cmd.Execute(state); err != nil {
+		return nil, err
+	}
+	return &cmd.Result, nil
+}
+
+// Command prepares the item's script as a command, to be run inside a
+// transaction of the caller's choosing. The item is compiled once per
+// catalogue version.
+func (rt *Runtime) Command(ctx context.Context, item Item, params map[string]interface{}) (*ScriptCommand, error) {
+	programs := rt.Programs
+	if programs == nil {
+		programs = Programs
+	}
+	program, err := programs.Program(item)
+	if err != nil {
+		return nil, err
+	}
+	return rt.newCommand(ctx, program, params), nil
+}
+
+func (rt *Runtime) newCommand(ctx context.Context, program *Program, params map[string]interface{}) *ScriptCommand {
+	return &ScriptCommand{Program: program, Params: params, RNG: rt.rng(), Limits: rt.Limits, Actions: rt.Actions, Ctx: ctx, Trace: rt.Trace}
+}
+
+// ScriptCommand runs an item script as a single command. Every action is
+// applied as a command of its own, on the board its spec targets, and undone
+// in reverse order if a later one fails, or when the whole script is undone.
+type ScriptCommand struct {
+	Program *Program
+	Params  map[string]interface{}
+	RNG     RNG
+	Limits  Limits
+	Actions ActionSet
+	// Ctx cancels the run. The command interface has no room for a context,
+	// so it travels with the command.
+	Ctx    context.Context
+	Trace  *Trace
+	Result Result
+
+	applied []appliedCommand
+}
+
+type appliedCommand struct {
+	board transaction.Board
+	cmd   transaction.Command
+}
+
+// Execute runs the script in a transaction of its own on a single board.
+func (c *ScriptCommand) Execute(state *game.GameState) error {
+	tx := transaction.NewTransaction()
+	tx.AddContext(c)
+	return tx.ExecuteContext(&transaction.Context{Own: state, Enemy: state})
+}
+
+func (c *ScriptCommand) Apply(tc *transaction.Context) error {
+	if err := c.Program.Check(c.Actions); err != nil {
+		return err
+	}
+	source := c.RNG
+	if source == nil {
+		source = rng.NewRandom()
+	}
+	parent := c.Ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	limits := c.Limits.withDefaults()
+	ctx, cancel := context.WithTimeout(parent, limits.Timeout)
+	defer cancel()
+
+	r := &scriptRun{
+		cmd:    c,
+		boards: tc,
+		budget: &budget{ctx: ctx, limits: limits},
+	}
+	intn := source.Intn
+	if c.Trace != nil {
+		c.Trace.Steps = nil
+		r.tracer = &tracer{trace: c.Trace, current: -1}
+		intn = func(n int) int {
+			v := source.Intn(n)
+			r.tracer.draw(n, v)
+			return v
+		}
+	}
+	r.env = &evalEnv{params: c.Params, intn: intn, onEval: r.budget.evaluation}
+	c.applied = nil
+	c.Result = Result{}
+	if err := r.run(c.Program.ops); err != nil {
+		c.Undo(tc)
+		return err
+	}
+	return nil
+}
+
+func (c *ScriptCommand) Undo(tc *transaction.Context) {
+	for i := len(c.applied) - 1; i >= 0; i-- {
+		if gs, err := tc.State(c.applied[i].board); err == nil {
+			c.applied[i].cmd.Undo(gs)
+		}
+	}
+	c.applied = nil
+}
+
+type scriptRun struct {
+	cmd    *ScriptCommand
+	boards *transaction.Context
+	env    *evalEnv
+	budget *budget
+	tracer *tracer
+}
+
+// board returns the board of the user or of the enemy.
+func (r *scriptRun) board(b transaction.Board) (*game.GameState, error) {
+	return r.boards.State(b)
+}
+
+func (r *scriptRun) effect(action string, board transaction.Board, args map[string]interface{}, outcome string, cells ...game.Coord) *Effect {
+	r.cmd.Result.Effects = append(r.cmd.Result.Effects, Effect{Action: action, Args: args, Cells: cells, Outcome: outcome, Own: board == transaction.OwnBoard})
+	return &r.cmd.Result.Effects[len(r.cmd.Result.Effects)-1]
+}
+
+// result records the value of a query action and exposes it to the rest of
+// the script as RESULT.
+func (r *scriptRun) result(action string, board transaction.Board, args map[string]interface{}, val int, cells ...game.Coord) {
+	r.env.set("RESULT", float64(val))
+	r.effect(action, board, args, OutcomeQueried, cells...).Value = &val
+}
+
+func (r *scriptRun) apply(board transaction.Board, cmd transaction.Command) error {
+	if err := r.budget.mutation(); err != nil {
+		return err
+	}
+	gs, err := r.board(board)
+	if err != nil {
+		return err
+	}
+	if err := cmd.Apply(gs); err != nil {
+		return err
+	}
+	r.cmd.applied = append(r.cmd.applied, appliedCommand{board: board, cmd: cmd})
+	return nil
+}
+
+func (r *scriptRun) run(ops []*op) error {
+	for _, o := range ops {
+		if err := r.step(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *scriptRun) step(o *op) error {
+	if err := r.budget.action(); err != nil {
+		return err
+	}
+	return r.traced(o, func() error {
+		if o.handler == nil {
+			return fmt.Errorf("unknown action: %s", o.Name)
+		}
+		return o.handler(r, o)
+	})
+}
+
+// args evaluates the arguments of o, recording them in the trace.
+func (r *scriptRun) args(o *op) (map[string]interface{}, error) {
+	args, err := o.evalArgs(r.env)
+	if err != nil {
+		return nil, err
+	}
+	if r.tracer != nil {
+		for k, v := range args {
+			r.tracer.arg(k, v)
+		}
+	}
+	return args, nil
+}
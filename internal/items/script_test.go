@@ -0,0 +1,115 @@
+package items
+
+import (
+	"context"
+	"lesta-battleship/server-core/internal/game"
+	"reflect"
+	"testing"
+)
+
+func TestRunScript_RollsBackOnFailure(t *testing.T) {
+	state := game.NewGameState()
+	ship := game.Ship{ID: "s1", Type: game.Destroyer, Coords: []game.Coord{{X: 1, Y: 1}, {X: 1, Y: 2}}}
+	state.Ships[ship.ID] = ship
+	state.Field[1][1] = game.ShipCell
+	state.Field[1][2] = game.Hit
+	before := state.Clone()
+
+	script := `[
+		{"OPEN_CELL": {"x": "5", "y": "5"}},
+		{"MAKE_SHOT": {"x": "6", "y": "6"}},
+		{"SET_SHIP_COORDINATES": {"x": "1", "y": "1", "x2": "1", "y2": "4"}},
+		{"SET_CELL_STATUS": {"x": "0", "y": "0", "status": "ship"}},
+		{"SET_SHIP_COORDINATES": {"x": "1", "y": "4", "x2": "9", "y2": "4"}}
+	]`
+	if _, err := RunScript(context.Background(), script, state, nil); err == nil {
+		t.Fatal("expected the last move to fail out of bounds")
+	}
+	if state.Field != before.Field {
+		t.Errorf("field changed after failed script:\nbefore %v\nafter  %v", before.Field, state.Field)
+	}
+	if !reflect.DeepEqual(state.Ships, before.Ships) || len(state.ShotsMade) != 0 {
+		t.Errorf("ships or shots changed after failed script: %+v %v", state.Ships, state.ShotsMade)
+	}
+}
+
+func TestOpenCell_ConsultsEffects(t *testing.T) {
+	state := game.NewGameState()
+	state.Ships["s1"] = game.Ship{ID: "s1", Type: game.Submarine, Coords: []game.Coord{{X: 8, Y: 2}}}
+	state.Field[8][2] = game.ShipCell
+
+	res, err := RunScript(context.Background(), `[
+		{"ADD_DECOY": {"x": "1", "y": "2", "turns": "2"}},
+		{"ADD_SONAR": {"y": "2", "turns": "2"}},
+		{"OPEN_CELL": {"x": "1", "y": "2"}},
+		{"OPEN_CELL": {"x": "3", "y": "2"}},
+		{"OPEN_CELL": {"x": "3", "y": "3"}}
+	]`, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoy, water, otherRow := res.Effects[2], res.Effects[3], res.Effects[4]
+	if decoy.Outcome != OutcomeRevealedShip {
+		t.Errorf("decoy scouted as %s, want %s", decoy.Outcome, OutcomeRevealedShip)
+	}
+	for _, e := range []Effect{decoy, water} {
+		if e.Value == nil || *e.Value != 1 {
+			t.Errorf("%v: sonar reading %v, want 1", e.Cells, e.Value)
+		}
+	}
+	if otherRow.Value != nil {
+		t.Errorf("row without sonar reported %d", *otherRow.Value)
+	}
+}
+
+func TestRunScript_StatusEffects(t *testing.T) {
+	state := game.NewGameState()
+	ship := game.Ship{ID: "s1", Type: game.Destroyer, Coords: []game.Coord{{X: 1, Y: 1}, {X: 2, Y: 1}}}
+	state.Ships[ship.ID] = ship
+	state.Field[1][1] = game.ShipCell
+	state.Field[2][1] = game.ShipCell
+
+	result, err := RunScript(context.Background(), `[
+		{"ADD_SHIELD": {"x": "1", "y": "1", "turns": "2"}},
+		{"ADD_DECOY": {"x": "5", "y": "5", "turns": "2"}},
+		{"ADD_SONAR": {"y": "1", "turns": "1"}}
+	]`, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := result.Effects[2].Value; v == nil || *v != 2 {
+		t.Errorf("sonar reported %v, want 2", v)
+	}
+	if got := game.OpenCell(5, 5, state); got != "ship" {
+		t.Errorf("decoy opened as %q, want ship", got)
+	}
+
+	shots := []struct {
+		target game.Coord
+		hit    bool
+		cell   game.CellState
+	}{
+		{game.Coord{X: 2, Y: 1}, false, game.Shielded}, // shield absorbs the shot
+		{game.Coord{X: 5, Y: 5}, true, game.Miss},      // decoy reports a hit once
+	}
+	for _, s := range shots {
+		cmd := &game.ShootCommand{Target: s.target}
+		if err := cmd.Apply(state); err != nil {
+			t.Fatal(err)
+		}
+		if cmd.Hit != s.hit || state.Field[s.target.X][s.target.Y] != s.cell {
+			t.Errorf("shot at %v: hit=%v cell=%v, want %v %v", s.target, cmd.Hit, state.Field[s.target.X][s.target.Y], s.hit, s.cell)
+		}
+	}
+	if count, ok := state.SonarReading(1); !ok || count != 1 {
+		t.Errorf("sonar reading = %d, %v; want 1, true", count, ok)
+	}
+	state.TickEffects()
+	if _, ok := state.SonarReading(1); ok || len(state.Effects) != 0 {
+		t.Errorf("effects left after expiry: %+v", state.Effects)
+	}
+
+	if _, err := RunScript(context.Background(), `[{"ADD_SHIELD": {"x": "7", "y": "7", "turns": "1"}}]`, state, nil); err == nil {
+		t.Error("expected shield on an empty cell to fail")
+	}
+}
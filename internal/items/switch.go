@@ -0,0 +1,72 @@
+package items
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// SwitchParam is the script param SWITCH_CASE branches on.
+const SwitchParam = "direction"
+
+// DefaultBranch is taken when no branch matches the switch param.
+const DefaultBranch = "default"
+
+// isSwitch reports whether name is the branching construct. The catalogue
+// spells it SWICH_CASE, so both spellings are accepted.
+func isSwitch(name string) bool {
+	return name == "SWITCH_CASE" || name == "SWICH_CASE"
+}
+
+// parseBranches decodes the branches of a SWITCH_CASE.
+func parseBranches(raw json.RawMessage) (map[string][]Action, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return nil, fmt.Errorf("branches must be an object: %w", err)
+	}
+	branches := make(map[string][]Action, len(obj))
+	for key, rawBranch := range obj {
+		list, err := parseActionList(rawBranch)
+		if err != nil {
+			return nil, fmt.Errorf("branch %s: %w", key, err)
+		}
+		branches[key] = list
+	}
+	return branches, nil
+}
+
+// parseActionList decodes either a list of actions or a single action.
+func parseActionList(raw json.RawMessage) ([]Action, error) {
+	var list []Action
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single Action
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil, err
+		}
+		list = []Action{single}
+	}
+	return list, nil
+}
+
+func runSwitch(r *scriptRun, o *op) error {
+	val, ok := r.env.params[SwitchParam]
+	if ok && r.tracer != nil {
+		r.tracer.arg(SwitchParam, val)
+	}
+	if ok {
+		if branch, ok := o.branches[switchKey(val)]; ok {
+			return r.run(branch)
+		}
+	}
+	if branch, ok := o.branches[DefaultBranch]; ok {
+		return r.run(branch)
+	}
+	return fmt.Errorf("%s: no branch for %s=%v", o.Name, SwitchParam, r.env.params[SwitchParam])
+}
+
+func switchKey(val interface{}) string {
+	if f, ok := toFloat(val); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(val)
+}
@@ -0,0 +1,83 @@
+package items
+
+import (
+	"context"
+	"lesta-battleship/server-core/internal/game"
+	"testing"
+)
+
+func TestSwitchCase_Branches(t *testing.T) {
+	script := `[
+		{"SWITCH_CASE": {
+			"1": {"OPEN_CELL": {"x": "x", "y": "y"}},
+			"2": [{"OPEN_CELL": {"x": "x", "y": "y"}}, {"OPEN_CELL": {"x": "x+1", "y": "y"}}],
+			"default": {"OPEN_CELL": {"x": "0", "y": "0"}}
+		}}
+	]`
+	cases := []struct {
+		direction interface{}
+		opened    []game.Coord
+	}{
+		{1, []game.Coord{{X: 4, Y: 4}}},
+		{"2", []game.Coord{{X: 4, Y: 4}, {X: 5, Y: 4}}},
+		{7, []game.Coord{{X: 0, Y: 0}}},
+	}
+	for _, c := range cases {
+		state := game.NewGameState()
+		params := map[string]interface{}{"x": 4, "y": 4, "direction": c.direction}
+		if _, err := RunScript(context.Background(), script, state, params); err != nil {
+			t.Fatalf("direction %v: %v", c.direction, err)
+		}
+		for _, coord := range c.opened {
+			if state.Field[coord.X][coord.Y] != game.Revealed {
+				t.Errorf("direction %v: cell %v not revealed", c.direction, coord)
+			}
+		}
+	}
+
+	noDefault := `[{"SWICH_CASE": {"1": {"END_PLAYER_ACTION": "None"}}}]`
+	if _, err := RunScript(context.Background(), noDefault, game.NewGameState(), map[string]interface{}{"direction": 3}); err == nil {
+		t.Error("expected error when no branch matches and there is no default")
+	}
+}
+
+// TestCatalogue_AllItems runs every item of Items_logic2.json with every
+// direction its SWICH_CASE offers.
+func TestCatalogue_AllItems(t *testing.T) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directions := map[string]int{
+		"Крест Нахимова":  1,
+		"Ремонтный набор": 1,
+		"Боевой приказ":   2,
+		"Конь":            8,
+		"Ладья":           2,
+		"Слон":            2,
+		"Ферзь":           1,
+	}
+	if len(catalogue.Items) != len(directions) {
+		t.Fatalf("catalogue has %d items, test knows %d", len(catalogue.Items), len(directions))
+	}
+
+	for _, item := range catalogue.Items {
+		name := item.Name
+		for dir := 1; dir <= directions[name]; dir++ {
+			state := game.NewGameState()
+			ship := game.Ship{ID: "s1", Type: game.Destroyer, Coords: []game.Coord{{X: 1, Y: 1}, {X: 1, Y: 2}}}
+			state.Ships[ship.ID] = ship
+			state.Field[1][1] = game.ShipCell
+			state.Field[1][2] = game.ShipCell
+
+			params := map[string]interface{}{"x": 1, "y": 1, "x2": 5, "y2": 1, "direction": dir, "FIELD_SIZE": 10}
+			if name == "Конь" {
+				params["x"], params["y"] = 5, 5
+			}
+			if _, err := UseItem(item.ID, state, catalogue.Items, params); err != nil {
+				t.Errorf("%s (direction %d): %v", name, dir, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package items
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/rng"
+)
+
+// Trace records a script run step by step. Set Runtime.Trace to record a run;
+// the trace is filled in even when the run fails and is rolled back.
+type Trace struct {
+	Steps []TraceStep
+}
+
+// TraceStep is one executed action. Steps nested in SWITCH_CASE, IF or a loop
+// follow their parent with a greater Depth; only leaf actions carry board
+// changes and an outcome.
+type TraceStep struct {
+	Depth   int
+	Action  string
+	Line    int
+	RawArgs map[string]interface{}
+	Args    map[string]interface{}
+	Draws   []rng.Draw
+	Changes []game.CellChange
+	Outcome string
+	Err     error
+}
+
+// tracer fills a Trace while a script runs.
+type tracer struct {
+	trace   *Trace
+	current int
+	depth   int
+}
+
+func (t *tracer) draw(n, v int) {
+	if t.current >= 0 {
+		s := &t.trace.Steps[t.current]
+		s.Draws = append(s.Draws, rng.Draw{N: n, Value: v})
+	}
+}
+
+func (t *tracer) arg(key string, val interface{}) {
+	if t.current < 0 {
+		return
+	}
+	s := &t.trace.Steps[t.current]
+	if s.Args == nil {
+		s.Args = map[string]interface{}{}
+	}
+	s.Args[key] = val
+}
+
+// traced runs one step of r, recording it when tracing is on.
+func (r *scriptRun) traced(o *op, exec func() error) error {
+	t := r.tracer
+	if t == nil {
+		return exec()
+	}
+	// Changes are those of the board the step's action targets.
+	board, err := r.board(o.board)
+	if err != nil {
+		return err
+	}
+
+	t.trace.Steps = append(t.trace.Steps, TraceStep{
+		Depth:   t.depth,
+		Action:  o.Name,
+		Line:    o.Line,
+		RawArgs: o.Args,
+	})
+	parent, index := t.current, len(t.trace.Steps)-1
+	t.current = index
+	t.depth++
+	before := board.Field
+	effects := len(r.cmd.Result.Effects)
+
+	err = exec()
+
+	t.depth--
+	t.current = parent
+	s := &t.trace.Steps[index]
+	name := o.name
+	if !isControl(name) && !isSwitch(name) {
+		s.Changes = game.DiffFields(before, board.Field)
+		if len(r.cmd.Result.Effects) > effects {
+			s.Outcome = r.cmd.Result.Last()
+		}
+	}
+	s.Err = err
+	return err
+}
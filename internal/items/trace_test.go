@@ -0,0 +1,103 @@
+package items
+
+import (
+	"context"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/rng"
+	"testing"
+)
+
+const traceBoard = `
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ S ~ ~ ~ ~ ~ ~ ~ ~
+~ S ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ X S S ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ o
+`
+
+func TestParseBoard(t *testing.T) {
+	state, err := game.ParseBoard(traceBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Ships) != 2 || state.Field[6][4] != game.Hit || state.Field[9][9] != game.Miss {
+		t.Fatalf("ships %v, field %v", state.Ships, state.Field)
+	}
+	if ship, ok := state.ShipAt(game.Coord{X: 7, Y: 4}); !ok || ship.Type != game.Cruiser {
+		t.Errorf("ship at (7,4) = %+v", ship)
+	}
+
+	again, err := game.ParseBoard(game.FormatBoard(state))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Field != state.Field || len(again.Ships) != 2 {
+		t.Error("board does not survive a format and parse round trip")
+	}
+
+	for _, bad := range []string{
+		"~ ~ ~",
+		traceBoard + "~ ~ ~ ~ ~ ~ ~ ~ ~ ~\n",
+		"S S S S S ~ ~ ~ ~ ~\n" + traceBoard[21:],
+	} {
+		if _, err := game.ParseBoard(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	state, err := game.ParseBoard(traceBoard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trace := &Trace{}
+	rt := &Runtime{RNG: rng.New(1), Trace: trace}
+	script := `[
+		{"OPEN_CELL": {"x": "x", "y": "y"}},
+		{"IF": {"cond": "RESULT", "then": {"MAKE_SHOT": {"x": "x", "y": "y"}}}},
+		{"OPEN_CELL": {"x": "RAND()", "y": "0"}}
+	]`
+	if _, err := rt.RunScript(context.Background(), script, state, map[string]interface{}{"x": 1, "y": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(trace.Steps) != 4 {
+		t.Fatalf("got %d steps: %+v", len(trace.Steps), trace.Steps)
+	}
+	open, cond, shot, random := trace.Steps[0], trace.Steps[1], trace.Steps[2], trace.Steps[3]
+	if open.RawArgs["y"] != "y" || open.Args["y"] != 1.0 || open.Outcome != OutcomeRevealedShip || len(open.Changes) != 0 {
+		t.Errorf("open step = %+v", open)
+	}
+	if cond.Action != "IF" || cond.Args["cond"] != 1.0 || cond.Depth != 0 || cond.Changes != nil {
+		t.Errorf("if step = %+v", cond)
+	}
+	want := game.CellChange{Cell: game.Coord{X: 1, Y: 1}, From: game.ShipCell, To: game.Hit}
+	if shot.Depth != 1 || len(shot.Changes) != 1 || shot.Changes[0] != want || shot.Outcome != OutcomeHit {
+		t.Errorf("shot step = %+v", shot)
+	}
+	if len(random.Draws) != 1 || random.Draws[0].N != 10 || random.Args["x"] != float64(random.Draws[0].Value) {
+		t.Errorf("random step = %+v", random)
+	}
+}
+
+func TestTrace_Failure(t *testing.T) {
+	state := game.NewGameState()
+	trace := &Trace{}
+	rt := &Runtime{Trace: trace}
+	script := `[{"OPEN_CELL": {"x": "1", "y": "1"}}, {"MAKE_SHOT": {"x": "x", "y": "1"}}]`
+	if _, err := rt.RunScript(context.Background(), script, state, nil); err == nil {
+		t.Fatal("expected unknown variable x to fail")
+	}
+	if len(trace.Steps) != 2 || trace.Steps[0].Err != nil || trace.Steps[1].Err == nil {
+		t.Fatalf("steps = %+v", trace.Steps)
+	}
+	if len(trace.Steps[0].Changes) != 1 || state.Field[1][1] != game.Empty {
+		t.Error("trace should keep the change while the board is rolled back")
+	}
+}
@@ -0,0 +1,395 @@
+package items
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+type Diagnostic struct {
+	Item     string
+	Line     int
+	Severity Severity
+	Msg      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s: %s: %s", d.Line, d.Severity, d.Item, d.Msg)
+}
+
+// KnownParams are the params a client may pass when using an item.
+var KnownParams = []string{"x", "y", "x2", "y2", "status", SwitchParam}
+
+// BuiltinFunctions is the function table of the runtime, used when an item
+// is validated outside of a catalogue. Register adds every action to it.
+var BuiltinFunctions = map[string]FunctionDecl{
+	"RAND":      {},
+	"PREV_RAND": {},
+}
+
+var BuiltinVariables = map[string]VariableDecl{
+	"FIELD_SIZE": {Type: "int"},
+	"RESULT":     {Type: "int"},
+	"RESULT_X":   {Type: "int"},
+	"RESULT_Y":   {Type: "int"},
+}
+
+// Validate statically checks an item against the runtime's own function
+// table: action names, arguments, expressions and variable references.
+func Validate(item Item) []Diagnostic {
+	v := &validator{item: item, funcs: BuiltinFunctions, vars: BuiltinVariables}
+	return v.validate()
+}
+
+// Validate checks every item against the functions and variables declared in
+// the catalogue.
+func (c *Catalogue) Validate() []Diagnostic {
+	return c.ValidateMode("")
+}
+
+// ValidateMode is Validate that also reports actions the game mode disables.
+func (c *Catalogue) ValidateMode(mode string) []Diagnostic {
+	var diags []Diagnostic
+	for _, item := range c.Items {
+		v := &validator{item: item, funcs: c.Functions, vars: c.Variables, allowed: ModeActions(mode)}
+		diags = append(diags, v.validate()...)
+	}
+	return diags
+}
+
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+type validator struct {
+	item    Item
+	funcs   map[string]FunctionDecl
+	vars    map[string]VariableDecl
+	allowed ActionSet
+	locals  map[string]bool
+	diags   []Diagnostic
+}
+
+func (v *validator) report(line int, sev Severity, format string, args ...interface{}) {
+	if line == 0 {
+		line = v.item.Line
+	}
+	v.diags = append(v.diags, Diagnostic{Item: v.item.Name, Line: line, Severity: sev, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate() []Diagnostic {
+	actions := v.item.Actions
+	if actions == nil {
+		parsed, err := ParseScript(v.item.Script)
+		if err != nil {
+			v.report(0, SeverityError, "cannot parse script: %v", err)
+			return v.diags
+		}
+		actions = parsed
+	}
+	v.locals = map[string]bool{}
+	collectLocals(actions, v.locals)
+	v.actions(actions)
+	return v.diags
+}
+
+// collectLocals gathers the variables a script defines with LET and FOR.
+// Scoping is not tracked: a variable counts as known anywhere in the item.
+func collectLocals(actions []Action, locals map[string]bool) {
+	for _, a := range actions {
+		switch strings.ToUpper(a.Name) {
+		case "LET":
+			for k := range a.Args {
+				locals[k] = true
+			}
+		case "FOR":
+			if name, ok := a.Args["var"].(string); ok {
+				locals[name] = true
+			}
+		}
+		for _, body := range a.Branches {
+			collectLocals(body, locals)
+		}
+	}
+}
+
+func (v *validator) actions(actions []Action) {
+	for _, a := range actions {
+		v.action(a)
+	}
+}
+
+func (v *validator) action(a Action) {
+	name := strings.ToUpper(a.Name)
+	if isSwitch(name) {
+		if name == "SWICH_CASE" {
+			v.report(a.Line, SeverityWarning, "%s is a misspelling of SWITCH_CASE", a.Name)
+		}
+		v.branches(a)
+		return
+	}
+
+	if isControl(name) {
+		v.control(name, a)
+		return
+	}
+
+	decl, declared := v.funcs[name]
+	if !declared {
+		v.report(a.Line, SeverityError, "unknown function %s%s", a.Name, suggest(name, v.funcs))
+		return
+	}
+	registered, ok := lookupAction(name)
+	if !ok {
+		v.report(a.Line, SeverityError, "%s is not an action the runtime can execute", a.Name)
+		return
+	}
+	if !v.allowed.Allows(name) {
+		v.report(a.Line, SeverityError, "%s is disabled in this game mode", a.Name)
+	}
+
+	inputs := map[string]bool{}
+	for _, in := range decl.Input {
+		inputs[in] = true
+		if _, ok := a.Args[in]; ok {
+			continue
+		}
+		if isKnownParam(in) {
+			v.report(a.Line, SeverityWarning, "%s: argument %s is missing and will be taken from params", a.Name, in)
+		} else {
+			v.report(a.Line, SeverityError, "%s: missing argument %s", a.Name, in)
+		}
+	}
+
+	for _, key := range sortedKeys(a.Args) {
+		if !inputs[key] {
+			v.report(a.Line, SeverityError, "%s: unexpected argument %s", a.Name, key)
+			continue
+		}
+		spec, _ := registered.spec.arg(key)
+		spec.Name = key
+		v.arg(a, spec, a.Args[key])
+	}
+}
+
+func (v *validator) control(name string, a Action) {
+	if name == "LET" {
+		if len(a.Args) == 0 {
+			v.report(a.Line, SeverityError, "%s assigns no variables", a.Name)
+		}
+		for _, key := range sortedKeys(a.Args) {
+			if !isIdent(key) {
+				v.report(a.Line, SeverityError, "%s: invalid variable name %q", a.Name, key)
+				continue
+			}
+			v.arg(a, number(key), a.Args[key])
+		}
+		return
+	}
+
+	spec := controlArgs[name]
+	known := map[string]bool{}
+	for _, key := range spec.required {
+		known[key] = true
+		if _, ok := a.Args[key]; !ok {
+			v.report(a.Line, SeverityError, "%s: missing argument %s", a.Name, key)
+		}
+	}
+	for _, key := range spec.optional {
+		known[key] = true
+	}
+	for _, key := range sortedKeys(a.Args) {
+		switch {
+		case !known[key]:
+			v.report(a.Line, SeverityError, "%s: unexpected argument %s", a.Name, key)
+		case name == "FOR" && key == "var":
+			if s, ok := a.Args[key].(string); !ok || !isIdent(s) {
+				v.report(a.Line, SeverityError, "%s: invalid loop variable %v", a.Name, a.Args[key])
+			}
+		default:
+			v.arg(a, number(key), a.Args[key])
+		}
+	}
+
+	for _, key := range controlBodies[name] {
+		if name == "IF" && key == "else" {
+			continue
+		}
+		if len(a.Branches[key]) == 0 {
+			v.report(a.Line, SeverityWarning, "%s: %s is empty", a.Name, key)
+		}
+	}
+	for _, key := range sortedBranchKeys(a.Branches) {
+		v.actions(a.Branches[key])
+	}
+}
+
+func (v *validator) arg(a Action, spec ArgSpec, raw interface{}) {
+	key := spec.Name
+	switch val := raw.(type) {
+	case string:
+		if spec.Type == ArgString {
+			if strings.HasPrefix(val, "$") {
+				if !isKnownParam(val[1:]) {
+					v.report(a.Line, SeverityError, "%s: argument %s: unknown variable %s", a.Name, key, val[1:])
+				}
+			} else if len(spec.Values) > 0 && !contains(spec.Values, val) {
+				label := spec.Label
+				if label == "" {
+					label = key
+				}
+				v.report(a.Line, SeverityError, "%s: unknown %s %q (want %s)", a.Name, label, val, orList(spec.Values))
+			}
+			return
+		}
+		e, err := ParseExpr(val)
+		if err != nil {
+			v.report(a.Line, SeverityError, "%s: argument %s: %v", a.Name, key, err)
+			return
+		}
+		v.expr(a, key, e)
+	case map[string]interface{}:
+		for name := range val {
+			if len(val) != 1 || !exprFunctions[strings.ToUpper(name)] {
+				v.report(a.Line, SeverityError, "%s: argument %s: invalid call %v", a.Name, key, val)
+			}
+		}
+	case float64:
+	default:
+		v.report(a.Line, SeverityError, "%s: argument %s: unsupported value %v", a.Name, key, raw)
+	}
+}
+
+func (v *validator) expr(a Action, key string, e *Expr) {
+	walk(e.root, func(n node) {
+		switch n := n.(type) {
+		case *varNode:
+			if _, ok := v.vars[n.name]; !ok && !isKnownParam(n.name) && !v.locals[n.name] {
+				v.report(a.Line, SeverityError, "%s: argument %s: unknown variable %s at column %d", a.Name, key, n.name, n.pos)
+			}
+		case *callNode:
+			if !exprFunctions[n.name] {
+				v.report(a.Line, SeverityError, "%s: argument %s: unknown function %s at column %d", a.Name, key, n.name, n.pos)
+			} else if _, ok := v.funcs[n.name]; !ok && (n.name == "RAND" || n.name == "PREV_RAND") {
+				v.report(a.Line, SeverityError, "%s: argument %s: function %s is not declared", a.Name, key, n.name)
+			}
+		}
+	})
+}
+
+func (v *validator) branches(a Action) {
+	if len(a.Branches) == 0 {
+		v.report(a.Line, SeverityError, "%s has no branches", a.Name)
+	}
+	for _, key := range sortedBranchKeys(a.Branches) {
+		v.actions(a.Branches[key])
+	}
+}
+
+// CheckParams reports a param a client may not pass when using an item, or
+// one that is neither a number nor a string.
+func CheckParams(params map[string]interface{}) error {
+	for name, val := range params {
+		if !isKnownParam(name) {
+			return fmt.Errorf("unknown param %s", name)
+		}
+		if _, ok := toFloat(val); !ok {
+			if _, ok := val.(string); !ok {
+				return fmt.Errorf("invalid value for param %s", name)
+			}
+		}
+	}
+	return nil
+}
+
+func isKnownParam(name string) bool {
+	return contains(KnownParams, name)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// orList joins values as "a, b or c".
+func orList(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedBranchKeys(m map[string][]Action) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// suggest returns a "did you mean" hint for a misspelled function name.
+func suggest(name string, funcs map[string]FunctionDecl) string {
+	best, bestDist := "", 3
+	for candidate := range funcs {
+		if d := editDistance(name, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %s?)", best)
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,87 @@
+package items
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate_Item(t *testing.T) {
+	item := Item{Name: "broken", Script: `[
+		{"OPEN_CEL": {"x": "x", "y": "y"}},
+		{"MAKE_SHOT": {"x": "x", "z": "1"}},
+		{"OPEN_CELL": {"x": "x + depth", "y": "y +"}},
+		{"SET_CELL_STATUS": {"x": "1", "y": "1", "status": "lava"}},
+		{"SWITCH_CASE": {"1": {"END_PLAYER_ACTION": "None"}}}
+	]`}
+
+	want := []string{
+		"unknown function OPEN_CEL (did you mean OPEN_CELL?)",
+		"MAKE_SHOT: argument y is missing",
+		"MAKE_SHOT: unexpected argument z",
+		"unknown variable depth at column 5",
+		"argument y: unexpected end of expression at column 4",
+		`unknown cell status "lava"`,
+	}
+	diags := Validate(item)
+	if !HasErrors(diags) {
+		t.Fatal("expected errors")
+	}
+	for _, w := range want {
+		found := false
+		for _, d := range diags {
+			if strings.Contains(d.Msg, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing diagnostic %q in %v", w, diags)
+		}
+	}
+}
+
+func TestValidate_CatalogueLines(t *testing.T) {
+	data := []byte(`{
+  "functions": {
+    "OPEN_CELL": {"input": ["x", "y"], "description": ""}
+  },
+  "variables": {},
+  "items": {
+    "test": {"id": 1,
+      "actions": [
+        { "OPEN_CELL": {"x": "x", "y": "y"} },
+        { "SWITCH_CASE": {
+          "1": { "OPEN_CELL": {"x": "x"} },
+          "2": [
+            { "MAKE_SHOT": {"x": "x", "y": "y"} }
+          ]
+        } }
+      ]
+    }
+  }
+}`)
+	catalogue, err := LoadCatalogue(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := catalogue.Validate()
+	lines := map[int]string{}
+	for _, d := range diags {
+		lines[d.Line] = d.Msg
+	}
+	if !strings.Contains(lines[11], "argument y is missing") {
+		t.Errorf("line 11: got %q (all: %v)", lines[11], diags)
+	}
+	if !strings.Contains(lines[13], "unknown function MAKE_SHOT") {
+		t.Errorf("line 13: got %q (all: %v)", lines[13], diags)
+	}
+}
+
+func TestValidate_BundledCatalogue(t *testing.T) {
+	catalogue, err := LoadCatalogueFile("Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diags := catalogue.Validate(); HasErrors(diags) {
+		t.Errorf("bundled catalogue has errors: %v", diags)
+	}
+}
@@ -0,0 +1,91 @@
+package match
+
+import (
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+)
+
+// Agent plays for a player on the server, in place of a websocket client.
+type Agent interface {
+	// Fleet returns the ships to place, without IDs.
+	Fleet() ([]game.Ship, error)
+	// NextShot chooses the cell to fire at next.
+	NextShot() game.Coord
+	// Observe reports the outcome of the agent's own shot.
+	Observe(shot *Shot)
+}
+
+var ErrHasAgent = errors.New("player is played by the server")
+
+// AddAgent hands a player over to an agent. The agent's fleet is placed with
+// the same place_ship transactions a client sends, and the player is ready.
+// The caller must hold the room mutex.
+func (r *GameRoom) AddAgent(playerID string, a Agent) error {
+	player := r.Player(playerID)
+	if player == nil {
+		return ErrUnknownPlayer
+	}
+	ships, err := a.Fleet()
+	if err != nil {
+		return err
+	}
+	for _, ship := range ships {
+		if _, err := r.PlaceShip(playerID, ship); err != nil {
+			return err
+		}
+	}
+	player.Agent = a
+	player.Ready = true
+	return nil
+}
+
+// PlayAgents lets agents take their turns for as long as the game is on and
+// the turn is an agent's. An agent that chooses a cell the room rejects fires
+// at a legal cell instead, so a human's match never stalls on a bot. notify
+// is called after every shot. The caller must hold the room mutex.
+func (r *GameRoom) PlayAgents(notify func(*Shot)) error {
+	for r.Status == "playing" {
+		player := r.Player(r.Turn)
+		if player == nil || player.Agent == nil {
+			return nil
+		}
+		target := player.Agent.NextShot()
+		if err := r.ValidateFire(player.ID, target); err != nil {
+			if r.OnRejectedMove != nil {
+				r.OnRejectedMove(player.ID, err)
+			}
+			legal, ok := r.legalTarget(player.ID)
+			if !ok {
+				return err
+			}
+			target = legal
+		}
+		shot, err := r.Fire(player.ID, target)
+		if err != nil {
+			return err
+		}
+		player.Agent.Observe(shot)
+		if notify != nil {
+			notify(shot)
+		}
+	}
+	return nil
+}
+
+// legalTarget returns the first cell of the opponent's board the player may
+// fire at.
+func (r *GameRoom) legalTarget(playerID string) (game.Coord, bool) {
+	opponent := r.Opponent(playerID)
+	if opponent == nil {
+		return game.Coord{}, false
+	}
+	for x := range opponent.State.Field {
+		for y := range opponent.State.Field[x] {
+			c := game.Coord{X: x, Y: y}
+			if r.ValidateFire(playerID, c) == nil {
+				return c, true
+			}
+		}
+	}
+	return game.Coord{}, false
+}
@@ -0,0 +1,61 @@
+package match
+
+import "lesta-battleship/server-core/internal/transaction"
+
+// Kinds of room events.
+const (
+	EventShipPlaced  = "ship_placed"
+	EventShipRemoved = "ship_removed"
+	EventShot        = "shot"
+	EventSalvo       = "salvo"
+	EventItemUsed    = "item_used"
+	EventGameEnd     = "game_end"
+)
+
+// Event is a domain event of a room: the outcome of a committed transaction.
+type Event struct {
+	Kind   string
+	Player string
+	// Value is a game.Ship for ship events, a *Shot, a *Salvo, an *ItemUse,
+	// or the winner's ID for game_end.
+	Value any
+
+	// data is what the journal records of the event.
+	data map[string]any
+}
+
+// Subscribe registers fn to receive every event of the room. fn runs under
+// the room mutex.
+func (r *GameRoom) Subscribe(fn func(Event)) {
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// outcome is queued with a room transaction and settled by the room's commit
+// hook once the transaction commits, which may pass the turn or end the game.
+type outcome interface {
+	settle(r *GameRoom, playerID string) []Event
+}
+
+// commit settles the outcomes of a committed transaction, journals their
+// events and publishes them. It runs as the room's OnCommit hook.
+func (r *GameRoom) commit(ctx *transaction.Context) {
+	for _, e := range ctx.Events() {
+		o, ok := e.(outcome)
+		if !ok {
+			continue
+		}
+		for _, event := range o.settle(r, ctx.PlayerID) {
+			r.Journal.Append(event.Player, event.Kind, event.data)
+			for _, fn := range r.subscribers {
+				fn(event)
+			}
+		}
+	}
+}
+
+// end ends the game with the given winner and returns its game_end event.
+func (r *GameRoom) end(winner string) Event {
+	r.Status = "ended"
+	r.WinnerID = winner
+	return Event{Kind: EventGameEnd, Player: winner, Value: winner, data: map[string]any{"winner": winner}}
+}
@@ -0,0 +1,81 @@
+package match
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"slices"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	// Sinks the rest of player 2's fleet.
+	barrage := items.Item{ID: 1, Script: `[
+		{"MAKE_SHOT": {"x": "1", "y": "1"}},
+		{"MAKE_SHOT": {"x": "1", "y": "2"}},
+		{"MAKE_SHOT": {"x": "7", "y": "4"}},
+		{"MAKE_SHOT": {"x": "8", "y": "4"}}
+	]`}
+	room := fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: barrage.ID, Charges: 1}}})
+	var events []Event
+	room.Subscribe(func(e Event) { events = append(events, e) })
+
+	if _, err := room.Fire("p2", game.Coord{X: 0, Y: 0}); err == nil {
+		t.Fatal("expected a shot out of turn to fail")
+	}
+	if len(events) != 0 {
+		t.Fatalf("a failed shot published %v", events)
+	}
+
+	if _, err := room.UseItem("p1", barrage.ID, []items.Item{barrage}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Kind != EventItemUsed || events[1].Kind != EventGameEnd {
+		t.Fatalf("events = %+v", events)
+	}
+	if use := events[0].Value.(*ItemUse); !use.GameOver {
+		t.Errorf("item use = %+v", use)
+	}
+	if room.Status != "ended" || room.WinnerID != "p1" || events[1].Value != "p1" {
+		t.Errorf("status %s, winner %s", room.Status, room.WinnerID)
+	}
+	kinds := []string{}
+	for _, e := range room.Journal.Entries() {
+		kinds = append(kinds, e.Kind)
+	}
+	if want := []string{"match_created", EventItemUsed, EventGameEnd}; !slices.Equal(kinds, want) {
+		t.Errorf("journal = %v, want %v", kinds, want)
+	}
+}
+
+func TestEvents_Ships(t *testing.T) {
+	room := NewGameRoom(Options{RoomID: "r1", Player1: "p1", Player2: "p2"})
+	var events []Event
+	room.Subscribe(func(e Event) { events = append(events, e) })
+
+	ship, err := room.PlaceShip("p1", game.Ship{Type: game.Destroyer, Coords: []game.Coord{{X: 0, Y: 0}, {X: 0, Y: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := room.RemoveShip("p1", ship.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Kind != EventShipPlaced || events[1].Kind != EventShipRemoved {
+		t.Fatalf("events = %+v", events)
+	}
+	if placed := events[0].Value.(game.Ship); placed.ID != ship.ID || placed.ID == "" {
+		t.Errorf("placed %+v, want %+v", placed, ship)
+	}
+	if removed := events[1].Value.(game.Ship); removed.ID != ship.ID {
+		t.Errorf("removed %+v", removed)
+	}
+
+	// The journal has one entry per event, with its data.
+	entries := room.Journal.Entries()[1:] // after match_created
+	if len(entries) != 2 || entries[0].Kind != EventShipPlaced || entries[1].Kind != EventShipRemoved {
+		t.Fatalf("journal = %+v", entries)
+	}
+	if entries[0].Data["ship"].(game.Ship).ID != ship.ID || entries[1].Data["ship_id"] != ship.ID {
+		t.Errorf("journal = %+v", entries)
+	}
+}
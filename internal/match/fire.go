@@ -0,0 +1,212 @@
+package match
+
+import (
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/transaction"
+)
+
+// Shot is the outcome of a fire action.
+type Shot struct {
+	Player   string
+	Target   game.Coord
+	Hit      bool
+	Sunk     bool
+	GameOver bool
+	NextTurn string
+	// ShotsLeft is what remains of the player's salvo after the shot.
+	ShotsLeft int
+	// Sonar is the sonar reading of the target row, if one is active.
+	Sonar *int
+}
+
+// Salvo is the outcome of a fire_salvo action.
+type Salvo struct {
+	Player   string
+	Shots    []Shot
+	GameOver bool
+	NextTurn string
+}
+
+// Fire shoots at the opponent of the player inside a room transaction, then
+// ends the game or, once the player's shots for the turn are used, passes the
+// turn. The caller must hold the room mutex.
+func (r *GameRoom) Fire(playerID string, target game.Coord) (*Shot, error) {
+	tx, ctx, cmd, err := r.fireTx(playerID, target)
+	if err != nil {
+		return nil, err
+	}
+	f := &fired{cmd: cmd, left: r.shotsLeft(playerID)}
+	ctx.Emit(f)
+	if err := tx.ExecuteContext(ctx); err != nil {
+		return nil, err
+	}
+	return f.shot, nil
+}
+
+// ValidateFire reports whether Fire would succeed, without touching the
+// boards. The caller must hold the room mutex.
+func (r *GameRoom) ValidateFire(playerID string, target game.Coord) error {
+	tx, ctx, _, err := r.fireTx(playerID, target)
+	if err != nil {
+		return err
+	}
+	return tx.ValidateContext(ctx)
+}
+
+func (r *GameRoom) fireTx(playerID string, target game.Coord) (*transaction.Transaction, *transaction.Context, *game.ShootCommand, error) {
+	if r.Opponent(playerID) == nil {
+		return nil, nil, nil, ErrUnknownPlayer
+	}
+	cmd := &game.ShootCommand{Target: target}
+	tx, ctx := r.Tx(playerID, ActionFire)
+	tx.AddOn(transaction.EnemyBoard, cmd)
+	return tx, ctx, cmd, nil
+}
+
+// FireSalvo fires the player's whole salvo for the turn in one transaction:
+// either every shot lands or none does. It takes exactly as many targets as
+// the player has shots left. The caller must hold the room mutex.
+func (r *GameRoom) FireSalvo(playerID string, targets []game.Coord) (*Salvo, error) {
+	tx, ctx, cmds, err := r.salvoTx(playerID, targets)
+	if err != nil {
+		return nil, err
+	}
+	f := &salvoFired{cmds: cmds}
+	ctx.Emit(f)
+	if err := tx.ExecuteContext(ctx); err != nil {
+		return nil, err
+	}
+	return f.salvo, nil
+}
+
+// ValidateSalvo reports whether FireSalvo would succeed, without touching the
+// boards. The caller must hold the room mutex.
+func (r *GameRoom) ValidateSalvo(playerID string, targets []game.Coord) error {
+	tx, ctx, _, err := r.salvoTx(playerID, targets)
+	if err != nil {
+		return err
+	}
+	return tx.ValidateContext(ctx)
+}
+
+func (r *GameRoom) salvoTx(playerID string, targets []game.Coord) (*transaction.Transaction, *transaction.Context, []*game.ShootCommand, error) {
+	if !r.Rules.Salvo {
+		return nil, nil, nil, ErrNotSalvoMode
+	}
+	if r.Opponent(playerID) == nil {
+		return nil, nil, nil, ErrUnknownPlayer
+	}
+	if err := r.checkTurn(playerID); err != nil {
+		return nil, nil, nil, err
+	}
+	if left := r.shotsLeft(playerID); len(targets) != left {
+		return nil, nil, nil, fmt.Errorf("%w: want %d, got %d", ErrSalvoSize, left, len(targets))
+	}
+
+	cmds := make([]*game.ShootCommand, len(targets))
+	tx, ctx := r.Tx(playerID, ActionFireSalvo)
+	for i, target := range targets {
+		cmds[i] = &game.ShootCommand{Target: target}
+		tx.AddOn(transaction.EnemyBoard, cmds[i])
+	}
+	return tx, ctx, cmds, nil
+}
+
+func (r *GameRoom) shot(playerID string, opponent *PlayerConn, cmd *game.ShootCommand, sunk bool) *Shot {
+	shot := &Shot{Player: playerID, Target: cmd.Target, Hit: cmd.Hit, Sunk: sunk}
+	if count, ok := opponent.State.SonarReading(cmd.Target.Y); ok {
+		shot.Sonar = &count
+	}
+	return shot
+}
+
+// hitLater reports whether a later shot of a salvo hit the ship at c, which
+// then sank with that shot rather than this one.
+func hitLater(gs *game.GameState, later []*game.ShootCommand, c game.Coord) bool {
+	ship, _ := gs.ShipAt(c)
+	for _, cmd := range later {
+		if other, ok := gs.ShipAt(cmd.Target); ok && cmd.Hit && other.ID == ship.ID {
+			return true
+		}
+	}
+	return false
+}
+
+type fired struct {
+	cmd  *game.ShootCommand
+	left int
+	shot *Shot
+}
+
+func (f *fired) settle(r *GameRoom, playerID string) []Event {
+	opponent := r.Opponent(playerID)
+	f.shot = r.shot(playerID, opponent, f.cmd, f.cmd.Hit && opponent.State.ShipSunk(f.cmd.Target))
+	f.shot.GameOver = opponent.State.ShipCellsLeft() == 0
+	if f.shot.GameOver {
+		f.shot.NextTurn = opponent.ID
+		return []Event{f.shot.event(), r.end(playerID)}
+	}
+	f.shot.ShotsLeft, f.shot.NextTurn = r.endShots(playerID, opponent, f.left-1, f.shot.Hit)
+	return []Event{f.shot.event()}
+}
+
+func (s *Shot) event() Event {
+	return Event{Kind: EventShot, Player: s.Player, Value: s, data: s.journal()}
+}
+
+func (s *Shot) journal() map[string]any {
+	return map[string]any{"x": s.Target.X, "y": s.Target.Y, "hit": s.Hit, "sunk": s.Sunk, "next_turn": s.NextTurn}
+}
+
+type salvoFired struct {
+	cmds  []*game.ShootCommand
+	salvo *Salvo
+}
+
+func (f *salvoFired) settle(r *GameRoom, playerID string) []Event {
+	opponent := r.Opponent(playerID)
+	salvo := &Salvo{Player: playerID, GameOver: opponent.State.ShipCellsLeft() == 0}
+	hit := false
+	for i, cmd := range f.cmds {
+		sunk := cmd.Hit && opponent.State.ShipSunk(cmd.Target) && !hitLater(opponent.State, f.cmds[i+1:], cmd.Target)
+		salvo.Shots = append(salvo.Shots, *r.shot(playerID, opponent, cmd, sunk))
+		hit = hit || cmd.Hit
+	}
+	if salvo.GameOver {
+		salvo.NextTurn = opponent.ID
+	} else {
+		_, salvo.NextTurn = r.endShots(playerID, opponent, 0, hit)
+	}
+	for i := range salvo.Shots {
+		salvo.Shots[i].GameOver = salvo.GameOver
+		salvo.Shots[i].NextTurn = salvo.NextTurn
+	}
+	f.salvo = salvo
+
+	shots := make([]map[string]any, len(salvo.Shots))
+	for i := range salvo.Shots {
+		shots[i] = salvo.Shots[i].journal()
+	}
+	events := []Event{{Kind: EventSalvo, Player: playerID, Value: salvo, data: map[string]any{"shots": shots, "next_turn": salvo.NextTurn}}}
+	if salvo.GameOver {
+		events = append(events, r.end(playerID))
+	}
+	return events
+}
+
+// endShots records that the player has left shots remaining, or ends the turn;
+// hit tells whether the turn's last shot or salvo hit. It returns the shots
+// left and who moves next.
+func (r *GameRoom) endShots(playerID string, opponent *PlayerConn, left int, hit bool) (int, string) {
+	if left > 0 {
+		r.ShotsLeft = left
+		return left, playerID
+	}
+	if hit && r.Rules.Turn == TurnContinueOnHit {
+		r.continueTurn()
+		return 0, playerID
+	}
+	r.PassTurn(opponent.ID)
+	return 0, r.Turn
+}
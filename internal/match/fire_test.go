@@ -0,0 +1,201 @@
+package match
+
+import (
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"slices"
+	"testing"
+)
+
+// Player 1 has three ships afloat, player 2 a destroyer at (1,1)-(1,2) and a
+// cruiser at (6,4)-(8,4) that is already hit once.
+const (
+	board1 = `
+S ~ S ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ S ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+`
+	board2 = `
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ S ~ ~ ~ ~ ~ ~ ~ ~
+~ S ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ X S S ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+~ ~ ~ ~ ~ ~ ~ ~ ~ ~
+`
+)
+
+func fireRoom(t *testing.T, mode string) *GameRoom {
+	t.Helper()
+	room := NewGameRoom(Options{RoomID: "r1", Mode: mode, Player1: "p1", Player2: "p2"})
+	for p, board := range map[*PlayerConn]string{room.Player1: board1, room.Player2: board2} {
+		state, err := game.ParseBoard(board)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.State = state
+	}
+	room.Status, room.Turn = "playing", "p1"
+	return room
+}
+
+func TestFire_Classic(t *testing.T) {
+	room := fireRoom(t, "")
+	shot, err := room.Fire("p1", game.Coord{X: 7, Y: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shot.Hit || shot.Sunk || shot.NextTurn != "p2" || room.Turn != "p2" {
+		t.Errorf("shot = %+v, turn %s", shot, room.Turn)
+	}
+	if _, err := room.FireSalvo("p2", []game.Coord{{X: 0, Y: 0}}); !errors.Is(err, ErrNotSalvoMode) {
+		t.Errorf("err = %v, want ErrNotSalvoMode", err)
+	}
+}
+
+func TestFire_SalvoOneAtATime(t *testing.T) {
+	room := fireRoom(t, ModeSalvo)
+	for i, want := range []int{2, 1} {
+		shot, err := room.Fire("p1", game.Coord{X: i, Y: 9})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shot.ShotsLeft != want || shot.NextTurn != "p1" || room.Turn != "p1" {
+			t.Fatalf("shot %d: %+v, turn %s", i, shot, room.Turn)
+		}
+	}
+	shot, err := room.Fire("p1", game.Coord{X: 2, Y: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shot.ShotsLeft != 0 || room.Turn != "p2" {
+		t.Fatalf("turn did not pass after the salvo: %+v", shot)
+	}
+
+	// Player 2 has two ships afloat.
+	if _, err := room.FireSalvo("p2", []game.Coord{{X: 5, Y: 5}}); !errors.Is(err, ErrSalvoSize) {
+		t.Errorf("err = %v, want ErrSalvoSize", err)
+	}
+}
+
+func TestFireSalvo(t *testing.T) {
+	room := fireRoom(t, ModeSalvo)
+	target := room.Player2.State
+	before := target.Field
+
+	if _, err := room.FireSalvo("p2", []game.Coord{{}, {X: 1}, {X: 2}}); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("err = %v, want ErrNotYourTurn", err)
+	}
+	if err := room.ValidateSalvo("p1", []game.Coord{{X: 7, Y: 4}, {X: 8, Y: 4}, {X: 7, Y: 4}}); err == nil {
+		t.Error("validation passed a salvo that fires twice at one cell")
+	}
+	if _, err := room.FireSalvo("p1", []game.Coord{{X: 7, Y: 4}, {X: 8, Y: 4}, {X: 7, Y: 4}}); err == nil {
+		t.Fatal("expected an error for a salvo that fires twice at one cell")
+	}
+	if target.Field != before || room.Turn != "p1" {
+		t.Fatal("a failed salvo was not rolled back")
+	}
+
+	salvo, err := room.FireSalvo("p1", []game.Coord{{X: 7, Y: 4}, {X: 8, Y: 4}, {X: 0, Y: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, sunk := 0, 0
+	for _, s := range salvo.Shots {
+		if s.Hit {
+			hits++
+		}
+		if s.Sunk {
+			sunk++
+		}
+	}
+	if hits != 2 || sunk != 1 || !salvo.Shots[1].Sunk {
+		t.Errorf("shots = %+v", salvo.Shots)
+	}
+	if salvo.NextTurn != "p2" || room.Turn != "p2" || salvo.GameOver {
+		t.Errorf("salvo = %+v, turn %s", salvo, room.Turn)
+	}
+}
+
+func TestFire_ContinueOnHit(t *testing.T) {
+	room := fireRoom(t, ModeHitAgain)
+	var moves []string
+	room.OnTurn = func(playerID string) { moves = append(moves, playerID) }
+
+	for _, c := range []game.Coord{{X: 7, Y: 4}, {X: 8, Y: 4}} {
+		shot, err := room.Fire("p1", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !shot.Hit || shot.NextTurn != "p1" || room.Turn != "p1" {
+			t.Fatalf("shot at %v = %+v, turn %s", c, shot, room.Turn)
+		}
+	}
+	shot, err := room.Fire("p1", game.Coord{X: 0, Y: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shot.NextTurn != "p2" || room.Turn != "p2" {
+		t.Errorf("a miss kept the turn: %+v", shot)
+	}
+	if want := []string{"p1", "p1", "p2"}; !slices.Equal(moves, want) {
+		t.Errorf("moves = %v, want %v", moves, want)
+	}
+}
+
+func TestTurnRuleOverride(t *testing.T) {
+	room := NewGameRoom(Options{RoomID: "r1", Mode: ModeSalvo, Player1: "p1", Player2: "p2", TurnRule: TurnContinueOnHit})
+	if !room.Rules.Salvo || room.Rules.Turn != TurnContinueOnHit {
+		t.Errorf("rules = %+v", room.Rules)
+	}
+	if rules := RulesFor("unknown"); rules.Salvo || rules.Turn != TurnAlternate {
+		t.Errorf("classic rules = %+v", rules)
+	}
+}
+
+func TestFire_ShieldedCellReadsAsMiss(t *testing.T) {
+	room := fireRoom(t, "")
+	shield := &game.AddEffectCommand{Effect: game.StatusEffect{Kind: game.EffectShield, Cell: game.Coord{X: 1, Y: 1}, TurnsLeft: 3}}
+	if err := shield.Apply(room.Player2.State); err != nil {
+		t.Fatal(err)
+	}
+	absorbed, water := game.Coord{X: 1, Y: 1}, game.Coord{X: 5, Y: 5}
+	for _, c := range []game.Coord{absorbed, water} {
+		room.Turn = "p1"
+		shot, err := room.Fire("p1", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if shot.Hit {
+			t.Fatalf("shot at %v hit", c)
+		}
+	}
+
+	// Validation must not tell the absorbed shot from a real miss.
+	room.Turn = "p1"
+	errAbsorbed := room.ValidateFire("p1", absorbed)
+	errWater := room.ValidateFire("p1", water)
+	if errAbsorbed == nil || errWater == nil || errAbsorbed.Error() != errWater.Error() {
+		t.Errorf("validate absorbed: %v, water: %v", errAbsorbed, errWater)
+	}
+
+	// The rest of the destroyer sinks it.
+	shot, err := room.Fire("p1", game.Coord{X: 1, Y: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shot.Hit || !shot.Sunk {
+		t.Errorf("shot = %+v", shot)
+	}
+}
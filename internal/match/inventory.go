@@ -0,0 +1,109 @@
+package match
+
+import (
+	"errors"
+	"sort"
+)
+
+var (
+	ErrItemNotOwned     = errors.New("item is not in your inventory")
+	ErrNoCharges        = errors.New("item has no charges left")
+	ErrItemOnCooldown   = errors.New("item is on cooldown")
+	ErrItemLimitReached = errors.New("item use limit for this turn reached")
+)
+
+// ItemSlot is one item of a player's inventory. Cooldown is the number of
+// the owner's turns the item is unavailable after a use.
+type ItemSlot struct {
+	ItemID       int `json:"item_id"`
+	Charges      int `json:"charges"`
+	Cooldown     int `json:"cooldown"`
+	CooldownLeft int `json:"cooldown_left"`
+}
+
+// Loadout is the initial inventory of a player as sent in /start-match.
+// PerTurnLimit caps item uses per turn; zero means no limit.
+type Loadout struct {
+	Items        []ItemSlot `json:"items"`
+	PerTurnLimit int        `json:"per_turn_limit"`
+}
+
+type Inventory struct {
+	slots        map[int]*ItemSlot
+	perTurnLimit int
+	usedThisTurn int
+}
+
+func NewInventory(l Loadout) *Inventory {
+	inv := &Inventory{slots: make(map[int]*ItemSlot), perTurnLimit: l.PerTurnLimit}
+	for _, slot := range l.Items {
+		if existing, ok := inv.slots[slot.ItemID]; ok {
+			existing.Charges += slot.Charges
+			continue
+		}
+		s := slot
+		s.CooldownLeft = 0
+		inv.slots[slot.ItemID] = &s
+	}
+	return inv
+}
+
+func (inv *Inventory) CanUse(itemID int) error {
+	if inv == nil {
+		return ErrItemNotOwned
+	}
+	slot, ok := inv.slots[itemID]
+	if !ok {
+		return ErrItemNotOwned
+	}
+	if slot.Charges <= 0 {
+		return ErrNoCharges
+	}
+	if slot.CooldownLeft > 0 {
+		return ErrItemOnCooldown
+	}
+	if inv.perTurnLimit > 0 && inv.usedThisTurn >= inv.perTurnLimit {
+		return ErrItemLimitReached
+	}
+	return nil
+}
+
+// Consume spends a charge of the item and starts its cooldown.
+func (inv *Inventory) Consume(itemID int) error {
+	if err := inv.CanUse(itemID); err != nil {
+		return err
+	}
+	slot := inv.slots[itemID]
+	slot.Charges--
+	if slot.Cooldown > 0 {
+		// The turn of the use itself does not count towards the cooldown.
+		slot.CooldownLeft = slot.Cooldown + 1
+	}
+	inv.usedThisTurn++
+	return nil
+}
+
+// EndTurn is called when the owner's turn ends.
+func (inv *Inventory) EndTurn() {
+	if inv == nil {
+		return
+	}
+	inv.usedThisTurn = 0
+	for _, slot := range inv.slots {
+		if slot.CooldownLeft > 0 {
+			slot.CooldownLeft--
+		}
+	}
+}
+
+func (inv *Inventory) Slots() []ItemSlot {
+	if inv == nil {
+		return nil
+	}
+	slots := make([]ItemSlot, 0, len(inv.slots))
+	for _, slot := range inv.slots {
+		slots = append(slots, *slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].ItemID < slots[j].ItemID })
+	return slots
+}
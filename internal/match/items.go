@@ -0,0 +1,252 @@
+package match
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/rng"
+	"lesta-battleship/server-core/internal/transaction"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// ItemUse is the outcome of a player using an item.
+type ItemUse struct {
+	Item     items.Item
+	Result   items.Result
+	GameOver bool
+	NextTurn string
+}
+
+// UseItem runs an item for a player inside a room transaction, so the room
+// rules apply as for a shot. Each action of the script runs on the board its
+// spec targets, the player's own or the opponent's. The player's
+// inventory must allow the use, the room mode the actions of the script and
+// items.CheckParams the params; a charge is only spent when the script
+// succeeds. The script draws from an RNG
+// forked from the room source, and the seed and every draw are journaled so
+// the use can be replayed with ReplayItem. The caller must hold the room
+// mutex.
+func (r *GameRoom) UseItem(playerID string, itemID int, catalogue []items.Item, params map[string]interface{}) (*ItemUse, error) {
+	player := r.Player(playerID)
+	if player == nil {
+		return nil, ErrUnknownPlayer
+	}
+	if err := player.Inventory.CanUse(itemID); err != nil {
+		return nil, err
+	}
+	item, err := items.FindItem(catalogue, itemID)
+	if err != nil {
+		return nil, err
+	}
+	// Params reach the script as variables, so one named like a game
+	// variable such as FIELD_SIZE would override it.
+	if err := items.CheckParams(params); err != nil {
+		return nil, err
+	}
+
+	source := r.RNG.Fork()
+	cmd, err := (&items.Runtime{RNG: source, Actions: items.ModeActions(r.Mode)}).Command(context.Background(), item, params)
+	if err != nil {
+		return nil, err
+	}
+	tx, ctx := r.Tx(playerID, ActionUseItem)
+	tx.AddContext(cmd)
+	own, err := ctx.State(transaction.OwnBoard)
+	if err != nil {
+		return nil, err
+	}
+	enemy, err := ctx.State(transaction.EnemyBoard)
+	if err != nil {
+		return nil, err
+	}
+	boards := itemBoards{Own: own.Snapshot(), Enemy: enemy.Snapshot()}
+	u := &itemUsed{item: item, params: params, source: source, boards: boards, cmd: cmd}
+	ctx.Emit(u)
+	if err := tx.ExecuteContext(ctx); err != nil {
+		data := u.journal()
+		data["error"] = err.Error()
+		r.Journal.Append(playerID, EventItemUsed, data)
+		return nil, err
+	}
+	return u.use, nil
+}
+
+// itemBoards are both boards of an item use, seen from its user.
+type itemBoards struct {
+	Own   game.Snapshot `json:"own"`
+	Enemy game.Snapshot `json:"enemy"`
+}
+
+type itemUsed struct {
+	item   items.Item
+	params map[string]interface{}
+	source *rng.Source
+	boards itemBoards // before the use
+	cmd    *items.ScriptCommand
+	use    *ItemUse
+}
+
+// journal records what ReplayItem needs. The seed is written as a string, as
+// a JSON number would lose its low bits.
+func (u *itemUsed) journal() map[string]any {
+	return map[string]any{
+		"item_id": u.item.ID,
+		"params":  u.params,
+		"seed":    strconv.FormatInt(u.source.Seed(), 10),
+		"draws":   u.source.Draws(),
+		"boards":  u.boards,
+		"effects": u.cmd.Result.Effects,
+	}
+}
+
+// settle spends the charge, then ends the game if either fleet is gone, or
+// passes the turn if the script ended it.
+func (u *itemUsed) settle(r *GameRoom, playerID string) []Event {
+	player, opponent := r.Player(playerID), r.Opponent(playerID)
+	player.Inventory.Consume(u.item.ID)
+	data := u.journal()
+	data["inventory"] = player.Inventory.Slots()
+
+	u.use = &ItemUse{Item: u.item, Result: u.cmd.Result, NextTurn: playerID}
+	event := Event{Kind: EventItemUsed, Player: playerID, Value: u.use, data: data}
+	winner := ""
+	if opponent.State.ShipCellsLeft() == 0 {
+		winner = playerID
+	} else if player.State.ShipCellsLeft() == 0 {
+		winner = opponent.ID
+	}
+	if winner != "" {
+		u.use.GameOver = true
+		return []Event{event, r.end(winner)}
+	}
+	if u.use.Result.EndsTurn {
+		r.PassTurn(opponent.ID)
+		u.use.NextTurn = opponent.ID
+	}
+	return []Event{event}
+}
+
+// ValidateItem reports whether the player may use the item now. It only
+// checks what needs no knowledge of the boards: the turn, the inventory, the
+// params and the actions the room mode allows. A dry run would give away the
+// enemy board to any script that branches on it. The caller must hold the
+// room mutex.
+func (r *GameRoom) ValidateItem(playerID string, itemID int, catalogue []items.Item, params map[string]interface{}) error {
+	player := r.Player(playerID)
+	if player == nil {
+		return ErrUnknownPlayer
+	}
+	if err := r.checkTurn(playerID); err != nil {
+		return err
+	}
+	if err := player.Inventory.CanUse(itemID); err != nil {
+		return err
+	}
+	item, err := items.FindItem(catalogue, itemID)
+	if err != nil {
+		return err
+	}
+	if err := items.CheckParams(params); err != nil {
+		return err
+	}
+	program, err := items.Programs.Program(item)
+	if err != nil {
+		return err
+	}
+	return program.Check(items.ModeActions(r.Mode))
+}
+
+var ErrBadJournalEntry = errors.New("malformed journal entry")
+
+// ReplayItem runs a journaled item use again from its recorded seed, on the
+// boards as they were before the use, and returns the result together with
+// the boards after the replay, seen from the user. The entry may have been
+// through JSON. The replay fails if it draws other numbers than the recorded
+// use did.
+func ReplayItem(entry JournalEntry, catalogue []items.Item) (*items.Result, *transaction.Context, error) {
+	if entry.Kind != EventItemUsed {
+		return nil, nil, fmt.Errorf("%w: %s is not an item use", ErrBadJournalEntry, entry.Kind)
+	}
+	itemID, ok := journalInt(entry.Data["item_id"])
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: item_id %v", ErrBadJournalEntry, entry.Data["item_id"])
+	}
+	seed, ok := journalInt(entry.Data["seed"])
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: seed %v", ErrBadJournalEntry, entry.Data["seed"])
+	}
+	var params map[string]interface{}
+	if raw, ok := entry.Data["params"]; ok && raw != nil {
+		if params, ok = raw.(map[string]interface{}); !ok {
+			return nil, nil, fmt.Errorf("%w: params %v", ErrBadJournalEntry, raw)
+		}
+	}
+	var boards itemBoards
+	if err := journalValue(entry.Data["boards"], &boards); err != nil {
+		return nil, nil, fmt.Errorf("%w: boards: %v", ErrBadJournalEntry, err)
+	}
+	var draws []rng.Draw
+	if err := journalValue(entry.Data["draws"], &draws); err != nil {
+		return nil, nil, fmt.Errorf("%w: draws: %v", ErrBadJournalEntry, err)
+	}
+
+	item, err := items.FindItem(catalogue, int(itemID))
+	if err != nil {
+		return nil, nil, err
+	}
+	source := rng.New(seed)
+	cmd, err := (&items.Runtime{RNG: source}).Command(context.Background(), item, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := &transaction.Context{Own: boards.Own.State(), Enemy: boards.Enemy.State()}
+	tx := transaction.NewTransaction()
+	tx.AddContext(cmd)
+	if err := tx.ExecuteContext(ctx); err != nil {
+		return nil, nil, err
+	}
+	if !slices.Equal(source.Draws(), draws) {
+		return nil, nil, errors.New("replay drew other numbers than the journaled use")
+	}
+	return &cmd.Result, ctx, nil
+}
+
+// journalInt reads an integer from a journal entry, as recorded or after a
+// JSON round trip.
+func journalInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) <= 1<<53 {
+			return int64(n), true
+		}
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(n, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// journalValue reads a value from a journal entry into v, whether it is still
+// of v's type or has been through JSON.
+func journalValue(raw any, v any) error {
+	if raw == nil {
+		return errors.New("missing")
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
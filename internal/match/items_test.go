@@ -0,0 +1,263 @@
+package match
+
+import (
+	"encoding/json"
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestRunItem_ReplayFromJournal(t *testing.T) {
+	catalogue, err := items.LoadCatalogueFile("../items/Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rook, _ := catalogue.ItemByName("Ладья")
+
+	room := fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: rook.ID, Charges: 1}}})
+	// Shots before the use must be part of the replayed board.
+	if _, err := room.Fire("p1", game.Coord{X: 1, Y: 1}); err != nil {
+		t.Fatal(err)
+	}
+	room.PassTurn("p1")
+	params := map[string]interface{}{"x": 3, "y": 3, "direction": 1}
+	if _, err := room.UseItem("p1", rook.ID, catalogue.Items, params); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := room.Journal.Entries()
+	last := entries[len(entries)-1]
+	if last.Kind != EventItemUsed {
+		t.Fatalf("last journal entry is %q", last.Kind)
+	}
+
+	_, replayed, err := ReplayItem(last, catalogue.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Enemy.Field != room.Player2.State.Field {
+		t.Errorf("replay differs:\n%v\n%v", replayed.Enemy.Field, room.Player2.State.Field)
+	}
+
+	raw, err := json.Marshal(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded JournalEntry
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if _, replayed, err = ReplayItem(decoded, catalogue.Items); err != nil {
+		t.Fatalf("replay after JSON: %v", err)
+	}
+	if replayed.Enemy.Field != room.Player2.State.Field {
+		t.Error("replay after JSON differs")
+	}
+
+	for _, key := range []string{"item_id", "seed", "boards"} {
+		broken := JournalEntry{Kind: last.Kind, Data: maps.Clone(last.Data)}
+		broken.Data[key] = "?"
+		if _, _, err := ReplayItem(broken, catalogue.Items); !errors.Is(err, ErrBadJournalEntry) {
+			t.Errorf("%s broken: err = %v, want ErrBadJournalEntry", key, err)
+		}
+	}
+}
+
+func TestUseItem_RunsEachActionOnItsBoard(t *testing.T) {
+	catalogue, err := items.LoadCatalogueFile("../items/Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Moves the user's ship, then fires at the enemy.
+	order, _ := catalogue.ItemByName("Боевой приказ")
+	room := fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: order.ID, Charges: 1}}})
+
+	params := map[string]interface{}{"x": 0, "y": 0, "x2": 0, "y2": 5, "direction": 1}
+	use, err := room.UseItem("p1", order.ID, catalogue.Items, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, enemy := room.Player1.State, room.Player2.State
+	if own.Field[0][0] != game.Empty || own.Field[0][5] != game.ShipCell {
+		t.Errorf("ship not moved on the user's board:\n%s", game.FormatBoard(own))
+	}
+	if enemy.Field[0][0] != game.Miss {
+		t.Errorf("shot did not reach the enemy board:\n%s", game.FormatBoard(enemy))
+	}
+	if e := use.Result.Effects; len(e) < 3 || !e[0].Own || e[2].Action != "MAKE_SHOT" || e[2].Own {
+		t.Errorf("effects = %+v", e)
+	}
+
+	entries := room.Journal.Entries()
+	_, replayed, err := ReplayItem(entries[len(entries)-1], catalogue.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Own.Field != own.Field || replayed.Enemy.Field != enemy.Field {
+		t.Error("replay differs")
+	}
+}
+
+func TestRunItem_EnforcesInventory(t *testing.T) {
+	catalogue, err := items.LoadCatalogueFile("../items/Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cross, _ := catalogue.ItemByName("Крест Нахимова")
+	room := NewGameRoom(Options{
+		RoomID:   "r1",
+		Player1:  "p1",
+		Player2:  "p2",
+		Loadout1: Loadout{Items: []ItemSlot{{ItemID: cross.ID, Charges: 2, Cooldown: 1}}},
+	})
+	for p, board := range map[*PlayerConn]string{room.Player1: board1, room.Player2: board2} {
+		if p.State, err = game.ParseBoard(board); err != nil {
+			t.Fatal(err)
+		}
+	}
+	room.Status, room.Turn = "playing", "p2"
+	params := map[string]interface{}{"x": 5, "y": 5}
+	use := func() error {
+		_, err := room.UseItem("p1", cross.ID, catalogue.Items, params)
+		return err
+	}
+
+	if err := use(); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("use out of turn: %v", err)
+	}
+	room.PassTurn("p1")
+
+	if err := use(); err != nil {
+		t.Fatal(err)
+	}
+	if err := use(); !errors.Is(err, ErrItemOnCooldown) {
+		t.Errorf("second use in the same turn: %v", err)
+	}
+	room.PassTurn("p2")
+	room.PassTurn("p1")
+	if err := use(); !errors.Is(err, ErrItemOnCooldown) {
+		t.Errorf("use on the next turn: %v", err)
+	}
+	room.PassTurn("p2")
+	room.PassTurn("p1")
+	if err := use(); err != nil {
+		t.Errorf("use after cooldown: %v", err)
+	}
+	room.PassTurn("p2")
+	room.PassTurn("p1")
+	room.PassTurn("p2")
+	room.PassTurn("p1")
+	if err := use(); !errors.Is(err, ErrNoCharges) {
+		t.Errorf("use without charges: %v", err)
+	}
+
+	room.PassTurn("p2")
+	if _, err := room.UseItem("p2", cross.ID, catalogue.Items, params); !errors.Is(err, ErrItemNotOwned) {
+		t.Errorf("use of an item not owned: %v", err)
+	}
+
+	// The per-turn limit counts uses of any item and resets with the turn.
+	scout := items.Item{ID: 100, Script: `[{"OPEN_CELL": {"x": "x", "y": "y"}}]`}
+	limited := append(slices.Clone(catalogue.Items), scout)
+	room = fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{
+		Items:        []ItemSlot{{ItemID: scout.ID, Charges: 5}, {ItemID: cross.ID, Charges: 1}},
+		PerTurnLimit: 2,
+	})
+	for i, c := range []game.Coord{{X: 5, Y: 5}, {X: 6, Y: 6}} {
+		if _, err := room.UseItem("p1", scout.ID, limited, map[string]interface{}{"x": c.X, "y": c.Y}); err != nil {
+			t.Fatalf("use %d within the limit: %v", i+1, err)
+		}
+	}
+	if _, err := room.UseItem("p1", scout.ID, limited, map[string]interface{}{"x": 7, "y": 7}); !errors.Is(err, ErrItemLimitReached) {
+		t.Errorf("third use in a turn: %v", err)
+	}
+	if _, err := room.UseItem("p1", cross.ID, limited, params); !errors.Is(err, ErrItemLimitReached) {
+		t.Errorf("use of another item over the limit: %v", err)
+	}
+	room.PassTurn("p2")
+	room.PassTurn("p1")
+	if _, err := room.UseItem("p1", scout.ID, limited, map[string]interface{}{"x": 7, "y": 7}); err != nil {
+		t.Errorf("use after the turn passed: %v", err)
+	}
+}
+
+func TestUseItem_RejectsUnknownParams(t *testing.T) {
+	catalogue, err := items.LoadCatalogueFile("../items/Items_logic2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rook, _ := catalogue.ItemByName("Ладья")
+	room := fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: rook.ID, Charges: 1}}})
+	own, enemy := room.Player1.State.Field, room.Player2.State.Field
+
+	// FIELD_SIZE=1 would force every RAND to 0.
+	params := map[string]interface{}{"x": 3, "y": 3, "direction": 1, "FIELD_SIZE": 1}
+	if _, err := room.UseItem("p1", rook.ID, catalogue.Items, params); err == nil {
+		t.Fatal("use with FIELD_SIZE passed")
+	}
+	if room.Player1.State.Field != own || room.Player2.State.Field != enemy {
+		t.Error("rejected use changed a board")
+	}
+	if err := room.Player1.Inventory.CanUse(rook.ID); err != nil {
+		t.Errorf("rejected use spent the charge: %v", err)
+	}
+}
+
+func TestValidateItem_RevealsNothing(t *testing.T) {
+	// A probe that fails only when there is a ship at (x, y).
+	probe := items.Item{ID: 1, Script: `[
+		{"IS_SHIP": {"x": "x", "y": "y"}},
+		{"IF": {"cond": "RESULT", "then": {"MAKE_SHOT": {"x": "99", "y": "99"}}}}
+	]`}
+	catalogue := []items.Item{probe}
+	room := fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: probe.ID, Charges: 1}}})
+
+	for _, c := range []game.Coord{{X: 1, Y: 1}, {X: 5, Y: 5}} {
+		params := map[string]interface{}{"x": c.X, "y": c.Y}
+		if err := room.ValidateItem("p1", probe.ID, catalogue, params); err != nil {
+			t.Errorf("probe at %v: %v", c, err)
+		}
+	}
+	if err := room.ValidateItem("p1", probe.ID, catalogue, map[string]interface{}{"z": 1}); err == nil {
+		t.Error("validation passed an unknown param")
+	}
+	if err := room.ValidateItem("p2", probe.ID, catalogue, nil); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("err = %v, want ErrNotYourTurn", err)
+	}
+	if _, err := room.UseItem("p1", probe.ID, catalogue, map[string]interface{}{"x": 1, "y": 1}); err == nil {
+		t.Error("the probe did not fail on a ship, so the test proves nothing")
+	}
+}
+
+func TestUseItem_SalvoDisallowsShots(t *testing.T) {
+	gun := items.Item{ID: 1, Script: `[{"MAKE_SHOT": {"x": "x", "y": "y"}}]`}
+	catalogue := []items.Item{gun}
+	params := map[string]interface{}{"x": 1, "y": 1}
+
+	room := fireRoom(t, ModeSalvo)
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: gun.ID, Charges: 1}}})
+	own, enemy := room.Player1.State.Field, room.Player2.State.Field
+	if err := room.ValidateItem("p1", gun.ID, catalogue, params); !errors.Is(err, items.ErrActionDisabled) {
+		t.Errorf("validate: err = %v, want ErrActionDisabled", err)
+	}
+	if _, err := room.UseItem("p1", gun.ID, catalogue, params); !errors.Is(err, items.ErrActionDisabled) {
+		t.Errorf("use: err = %v, want ErrActionDisabled", err)
+	}
+	if room.Player1.State.Field != own || room.Player2.State.Field != enemy {
+		t.Error("rejected use changed a board")
+	}
+
+	room = fireRoom(t, "")
+	room.Player1.Inventory = NewInventory(Loadout{Items: []ItemSlot{{ItemID: gun.ID, Charges: 1}}})
+	if _, err := room.UseItem("p1", gun.ID, catalogue, params); err != nil {
+		t.Errorf("classic use: %v", err)
+	}
+}
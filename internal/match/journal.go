@@ -0,0 +1,40 @@
+package match
+
+import (
+	"sync"
+	"time"
+)
+
+// JournalEntry is one recorded event of a match.
+type JournalEntry struct {
+	Seq      int            `json:"seq"`
+	Time     time.Time      `json:"time"`
+	PlayerID string         `json:"player_id,omitempty"`
+	Kind     string         `json:"kind"`
+	Data     map[string]any `json:"data,omitempty"`
+}
+
+// Journal is the append-only history of a match, detailed enough to replay
+// item uses and investigate disputes.
+type Journal struct {
+	mu      sync.Mutex
+	entries []JournalEntry
+}
+
+func (j *Journal) Append(playerID, kind string, data map[string]any) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.entries = append(j.entries, JournalEntry{
+		Seq:      len(j.entries) + 1,
+		Time:     time.Now(),
+		PlayerID: playerID,
+		Kind:     kind,
+		Data:     data,
+	})
+}
+
+func (j *Journal) Entries() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]JournalEntry(nil), j.entries...)
+}
@@ -2,6 +2,8 @@ package match
 
 import (
 	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/rng"
+	"lesta-battleship/server-core/internal/transaction"
 	"sync"
 	"time"
 
@@ -9,26 +11,81 @@ import (
 )
 
 type PlayerConn struct {
-	ID    string
-	Ready bool
-	State *game.GameState
-	Conn  *websocket.Conn
+	ID        string
+	Ready     bool
+	State     *game.GameState
+	Inventory *Inventory
+	Conn      *websocket.Conn
+	// Agent, if set, plays for the player instead of a client.
+	Agent Agent
 }
 
 type GameRoom struct {
-	RoomID    string
-	Mode      string
-	Player1   *PlayerConn
-	Player2   *PlayerConn
-	Status    string // waiting, ready, playing, ended
-	Turn      string // player ID
+	RoomID  string
+	Mode    string
+	Rules   Rules
+	Player1 *PlayerConn
+	Player2 *PlayerConn
+	Status  string // waiting, ready, playing, ended
+	Turn    string // player ID
+	// ShotsLeft is what remains of the current player's salvo; zero until
+	// they fire the first shot of their turn.
+	ShotsLeft int
+	// TurnStartedAt is when the current move began: a new turn, or another
+	// one after a hit. Turn timers count from it.
+	TurnStartedAt time.Time
+	// OnTurn, if set, is called whenever a move begins, with the player to
+	// move, so a turn timer can restart. It runs under the room mutex.
+	OnTurn    func(playerID string)
 	WinnerID  string
 	Mutex     sync.Mutex
+	Hooks     transaction.Hooks
+	RNG       *rng.Source
+	Journal   Journal
 	CreatedAt time.Time
+
+	// OnRejectedMove, if set, is called when an agent chooses a move the
+	// room rejects, before PlayAgents fires at a legal cell in its place. It
+	// runs under the room mutex.
+	OnRejectedMove func(playerID string, err error)
+
+	subscribers []func(Event)
 }
 
 var Rooms sync.Map
 
+func (r *GameRoom) Player(playerID string) *PlayerConn {
+	if r.Player1.ID == playerID {
+		return r.Player1
+	}
+	if r.Player2.ID == playerID {
+		return r.Player2
+	}
+	return nil
+}
+
+func (r *GameRoom) Opponent(playerID string) *PlayerConn {
+	if r.Player1.ID == playerID {
+		return r.Player2
+	}
+	if r.Player2.ID == playerID {
+		return r.Player1
+	}
+	return nil
+}
+
+// TxContext returns a transaction context seen from the given player's side.
+func (r *GameRoom) TxContext(playerID string) *transaction.Context {
+	ctx := &transaction.Context{RoomID: r.RoomID, PlayerID: playerID}
+	if p := r.Player(playerID); p != nil {
+		ctx.Own = p.State
+	}
+	if o := r.Opponent(playerID); o != nil {
+		ctx.Enemy = o.State
+	}
+	return ctx
+}
+
 func (p *PlayerConn) WriteMessage(msgType int, data []byte) error {
 	if p.Conn != nil {
 		return p.Conn.WriteMessage(msgType, data)
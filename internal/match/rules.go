@@ -0,0 +1,237 @@
+package match
+
+import (
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/rng"
+	"lesta-battleship/server-core/internal/transaction"
+	"time"
+)
+
+const MaxShips = 10
+
+const (
+	ActionPlaceShip  = "place_ship"
+	ActionRemoveShip = "remove_ship"
+	ActionFire       = "fire"
+	ActionFireSalvo  = "fire_salvo"
+	ActionUseItem    = "use_item"
+)
+
+// Game modes with rules of their own. Any other mode plays the classic rules.
+const (
+	// ModeSalvo gives a player one shot per turn for every ship they have
+	// afloat.
+	ModeSalvo = "salvo"
+	// ModeHitAgain lets a player fire again after a hit.
+	ModeHitAgain = "hit_again"
+)
+
+// TurnRule decides who moves once a player has fired their shots for the
+// turn.
+type TurnRule string
+
+const (
+	// TurnAlternate always hands the turn to the opponent.
+	TurnAlternate TurnRule = "alternate"
+	// TurnContinueOnHit keeps the turn with a player who hit or sank a ship.
+	TurnContinueOnHit TurnRule = "continue_on_hit"
+)
+
+func (t TurnRule) Valid() bool {
+	return t == TurnAlternate || t == TurnContinueOnHit
+}
+
+// Rules are what a game mode plays by.
+type Rules struct {
+	Salvo bool
+	Turn  TurnRule
+}
+
+var modeRules = map[string]Rules{
+	ModeSalvo:    {Salvo: true, Turn: TurnAlternate},
+	ModeHitAgain: {Turn: TurnContinueOnHit},
+}
+
+// modeItemActions are the actions item scripts may use in a mode. A salvo
+// is counted by the ships afloat, so an item must not fire shots beside it.
+var modeItemActions = map[string]items.ActionSet{
+	ModeSalvo: {Disable: []string{"MAKE_SHOT"}},
+}
+
+func init() {
+	for mode, set := range modeItemActions {
+		items.SetModeActions(mode, set)
+	}
+}
+
+// RulesFor returns the rules of a game mode; unknown modes play the classic
+// rules.
+func RulesFor(mode string) Rules {
+	rules, ok := modeRules[mode]
+	if !ok {
+		rules = Rules{Turn: TurnAlternate}
+	}
+	return rules
+}
+
+var (
+	ErrGameNotStarted = errors.New("game not started")
+	ErrNotYourTurn    = errors.New("not your turn")
+	ErrTooManyShips   = errors.New("maximum 10 ships allowed")
+	ErrAlreadyReady   = errors.New("you cannot remove ship after ready")
+	ErrUnknownPlayer  = errors.New("player is not in this room")
+	ErrNotSalvoMode   = errors.New("salvos are only fired in salvo mode")
+	ErrSalvoSize      = errors.New("wrong number of shots in salvo")
+)
+
+type Options struct {
+	RoomID  string
+	Mode    string
+	Player1 string
+	Player2 string
+	// Loadout1 and Loadout2 are the initial inventories of the players.
+	Loadout1 Loadout
+	Loadout2 Loadout
+	// Seed seeds the room RNG; zero picks a random seed.
+	Seed int64
+	// TurnRule, if set, overrides the turn rule of the mode.
+	TurnRule TurnRule
+}
+
+func NewGameRoom(opts Options) *GameRoom {
+	source := rng.NewRandom()
+	if opts.Seed != 0 {
+		source = rng.New(opts.Seed)
+	}
+	rules := RulesFor(opts.Mode)
+	if opts.TurnRule != "" {
+		rules.Turn = opts.TurnRule
+	}
+	room := &GameRoom{
+		RoomID:    opts.RoomID,
+		Mode:      opts.Mode,
+		Rules:     rules,
+		Player1:   &PlayerConn{ID: opts.Player1, State: game.NewGameState(), Inventory: NewInventory(opts.Loadout1)},
+		Player2:   &PlayerConn{ID: opts.Player2, State: game.NewGameState(), Inventory: NewInventory(opts.Loadout2)},
+		Status:    "waiting",
+		RNG:       source,
+		CreatedAt: time.Now(),
+	}
+	room.Hooks.Use(transaction.Hook{
+		BeforeApply: room.checkRules,
+		OnCommit:    room.commit,
+	})
+	room.Journal.Append("", "match_created", map[string]any{
+		"mode":    opts.Mode,
+		"rules":   rules,
+		"seed":    source.Seed(),
+		"player1": opts.Player1,
+		"player2": opts.Player2,
+	})
+	return room
+}
+
+// checkRules enforces the room rules for every transaction run with the room
+// hooks. The caller must hold the room mutex.
+func (r *GameRoom) checkRules(ctx *transaction.Context, step int, cmd transaction.ContextCommand) error {
+	player := r.Player(ctx.PlayerID)
+	if player == nil {
+		return ErrUnknownPlayer
+	}
+	switch ctx.Action {
+	case ActionPlaceShip:
+		own, err := ctx.State(transaction.OwnBoard)
+		if err != nil {
+			return err
+		}
+		if len(own.Ships) >= MaxShips {
+			return ErrTooManyShips
+		}
+	case ActionRemoveShip:
+		if player.Ready {
+			return ErrAlreadyReady
+		}
+	case ActionFire, ActionFireSalvo, ActionUseItem:
+		return r.checkTurn(player.ID)
+	}
+	return nil
+}
+
+func (r *GameRoom) checkTurn(playerID string) error {
+	if r.Status != "playing" {
+		return ErrGameNotStarted
+	}
+	if r.Turn != playerID {
+		return ErrNotYourTurn
+	}
+	return nil
+}
+
+// Tx returns a transaction bound to the room hooks together with a context
+// for the given player and action.
+func (r *GameRoom) Tx(playerID, action string) (*transaction.Transaction, *transaction.Context) {
+	ctx := r.TxContext(playerID)
+	ctx.Action = action
+	return transaction.NewTransaction().WithHooks(&r.Hooks), ctx
+}
+
+// Start begins the game with the given player's turn. The caller must hold
+// the room mutex.
+func (r *GameRoom) Start(first string) {
+	r.Status = "playing"
+	r.Turn = first
+	r.ShotsLeft = 0
+	r.startTurn()
+}
+
+// PassTurn ends the current player's turn and hands it to the given player,
+// counting down the status effects on that player's board. The caller must
+// hold the room mutex.
+func (r *GameRoom) PassTurn(to string) {
+	if p := r.Player(r.Turn); p != nil {
+		p.Inventory.EndTurn()
+	}
+	r.Turn = to
+	r.ShotsLeft = 0
+	if p := r.Player(to); p != nil {
+		p.State.TickEffects()
+	}
+	r.startTurn()
+}
+
+// continueTurn gives the current player another turn after a hit. It is the
+// same turn as far as inventories and status effects are concerned, but a
+// new move for turn timers.
+func (r *GameRoom) continueTurn() {
+	r.ShotsLeft = 0
+	r.startTurn()
+}
+
+func (r *GameRoom) startTurn() {
+	r.TurnStartedAt = time.Now()
+	if r.OnTurn != nil {
+		r.OnTurn(r.Turn)
+	}
+}
+
+// shotsPerTurn is how many shots the player fires in a turn: one, or in salvo
+// mode one per ship afloat.
+func (r *GameRoom) shotsPerTurn(playerID string) int {
+	if !r.Rules.Salvo {
+		return 1
+	}
+	if p := r.Player(playerID); p != nil {
+		return max(p.State.ShipsAfloat(), 1)
+	}
+	return 1
+}
+
+// shotsLeft is how many shots the player has left this turn.
+func (r *GameRoom) shotsLeft(playerID string) int {
+	if r.ShotsLeft > 0 {
+		return r.ShotsLeft
+	}
+	return r.shotsPerTurn(playerID)
+}
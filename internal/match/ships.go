@@ -0,0 +1,77 @@
+package match
+
+import (
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/transaction"
+)
+
+var ErrMissingShipID = errors.New("missing ship ID")
+
+// PlaceShip places a ship of the player, without an ID, and returns it as
+// placed. The caller must hold the room mutex.
+func (r *GameRoom) PlaceShip(playerID string, ship game.Ship) (game.Ship, error) {
+	tx, ctx, cmd := r.placeShipTx(playerID, ship)
+	ctx.Emit(placed{cmd})
+	if err := tx.ExecuteContext(ctx); err != nil {
+		return game.Ship{}, err
+	}
+	return cmd.Ship, nil
+}
+
+// ValidatePlaceShip returns the ship PlaceShip would place, without touching
+// the board. The caller must hold the room mutex.
+func (r *GameRoom) ValidatePlaceShip(playerID string, ship game.Ship) (game.Ship, error) {
+	tx, ctx, cmd := r.placeShipTx(playerID, ship)
+	if err := tx.ValidateContext(ctx); err != nil {
+		return game.Ship{}, err
+	}
+	return cmd.Ship, nil
+}
+
+func (r *GameRoom) placeShipTx(playerID string, ship game.Ship) (*transaction.Transaction, *transaction.Context, *game.PlaceShipCommand) {
+	cmd := &game.PlaceShipCommand{Ship: ship}
+	tx, ctx := r.Tx(playerID, ActionPlaceShip)
+	tx.Add(cmd)
+	return tx, ctx, cmd
+}
+
+// RemoveShip removes a ship of the player. The caller must hold the room
+// mutex.
+func (r *GameRoom) RemoveShip(playerID, shipID string) error {
+	if shipID == "" {
+		return ErrMissingShipID
+	}
+	tx, ctx, cmd := r.removeShipTx(playerID, shipID)
+	ctx.Emit(removed{cmd})
+	return tx.ExecuteContext(ctx)
+}
+
+// ValidateRemoveShip reports whether RemoveShip would succeed, without
+// touching the board. The caller must hold the room mutex.
+func (r *GameRoom) ValidateRemoveShip(playerID, shipID string) error {
+	if shipID == "" {
+		return ErrMissingShipID
+	}
+	tx, ctx, _ := r.removeShipTx(playerID, shipID)
+	return tx.ValidateContext(ctx)
+}
+
+func (r *GameRoom) removeShipTx(playerID, shipID string) (*transaction.Transaction, *transaction.Context, *game.RemoveShipCommand) {
+	cmd := &game.RemoveShipCommand{ShipID: shipID}
+	tx, ctx := r.Tx(playerID, ActionRemoveShip)
+	tx.Add(cmd)
+	return tx, ctx, cmd
+}
+
+type placed struct{ cmd *game.PlaceShipCommand }
+
+func (p placed) settle(r *GameRoom, playerID string) []Event {
+	return []Event{{Kind: EventShipPlaced, Player: playerID, Value: p.cmd.Ship, data: map[string]any{"ship": p.cmd.Ship}}}
+}
+
+type removed struct{ cmd *game.RemoveShipCommand }
+
+func (p removed) settle(r *GameRoom, playerID string) []Event {
+	return []Event{{Kind: EventShipRemoved, Player: playerID, Value: p.cmd.Backup, data: map[string]any{"ship_id": p.cmd.Backup.ID}}}
+}
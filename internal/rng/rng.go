@@ -0,0 +1,50 @@
+package rng
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Draw is one value handed out by a Source, together with its bound.
+type Draw struct {
+	N     int `json:"n"`
+	Value int `json:"value"`
+}
+
+// Source is a deterministic random source that remembers its seed and every
+// value it hands out, so whatever consumed it can be replayed bit-for-bit.
+// It is not safe for concurrent use.
+type Source struct {
+	seed  int64
+	r     *rand.Rand
+	draws []Draw
+}
+
+func New(seed int64) *Source {
+	return &Source{seed: seed, r: rand.New(rand.NewSource(seed))}
+}
+
+// NewRandom returns a Source seeded from the clock.
+func NewRandom() *Source {
+	return New(time.Now().UnixNano())
+}
+
+func (s *Source) Seed() int64 {
+	return s.seed
+}
+
+func (s *Source) Intn(n int) int {
+	v := s.r.Intn(n)
+	s.draws = append(s.draws, Draw{N: n, Value: v})
+	return v
+}
+
+func (s *Source) Draws() []Draw {
+	return append([]Draw(nil), s.draws...)
+}
+
+// Fork returns a new Source whose seed is drawn from s. A room forks one
+// Source per item use, so each use can be replayed on its own from its seed.
+func (s *Source) Fork() *Source {
+	return New(s.r.Int63())
+}
@@ -0,0 +1,25 @@
+package rng
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSource_Replay(t *testing.T) {
+	room := New(42)
+	use := room.Fork()
+	for i := 0; i < 5; i++ {
+		use.Intn(10)
+	}
+
+	replay := New(use.Seed())
+	for i := 0; i < 5; i++ {
+		replay.Intn(10)
+	}
+	if !reflect.DeepEqual(use.Draws(), replay.Draws()) {
+		t.Errorf("replay differs: %v vs %v", use.Draws(), replay.Draws())
+	}
+	if New(42).Fork().Seed() != use.Seed() {
+		t.Error("forks of equally seeded sources differ")
+	}
+}
@@ -0,0 +1,34 @@
+package sim
+
+import (
+	"io"
+	"lesta-battleship/server-core/internal/balance"
+	"strconv"
+)
+
+func table(reports []Report) *balance.Table {
+	t := balance.NewTable("a", "b", "games", "failed", "wins_a", "wins_b", "win_rate_a", "win_rate_b")
+	for _, r := range reports {
+		t.AddMetrics([]string{
+			r.A,
+			r.B,
+			strconv.Itoa(r.Games),
+			strconv.Itoa(r.Failed),
+			strconv.Itoa(r.WinsA),
+			strconv.Itoa(r.WinsB),
+			balance.FormatFloat(r.WinRateA),
+			balance.FormatFloat(r.WinRateB),
+		}, r.Metrics)
+	}
+	return t
+}
+
+// WriteCSV writes one row per series and metric, with a header.
+func WriteCSV(w io.Writer, reports []Report) error {
+	return table(reports).WriteCSV(w)
+}
+
+// WriteTable writes the rows of WriteCSV as an aligned text table.
+func WriteTable(w io.Writer, reports []Report) error {
+	return table(reports).WriteText(w)
+}
@@ -0,0 +1,190 @@
+// Package sim plays whole matches between agents in-process, through the same
+// room, commands and rules as a websocket match, and summarises the results.
+package sim
+
+import (
+	"errors"
+	"fmt"
+	"lesta-battleship/server-core/internal/balance"
+	"lesta-battleship/server-core/internal/bot"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/match"
+	"lesta-battleship/server-core/internal/rng"
+)
+
+// Player is a contestant. New makes a fresh agent for every game, drawing
+// from r.
+type Player struct {
+	Name string
+	New  func(r game.Intn) (match.Agent, error)
+}
+
+// BotPlayer plays with the bot of the given level.
+func BotPlayer(level string) Player {
+	return Player{Name: level, New: func(r game.Intn) (match.Agent, error) {
+		return bot.New(level, r)
+	}}
+}
+
+// Config describes a series of games between A and B. They take turns at
+// moving first.
+type Config struct {
+	A, B  Player
+	Games int
+	Seed  int64
+	Mode  string
+}
+
+// Game is the outcome of one match.
+type Game struct {
+	Seed   int64
+	First  string // "a" or "b"
+	Winner string // "a" or "b"
+	// Shots and Turns count both players; WinnerShots the winner's alone. A
+	// turn ends when the move passes to the other player or the game ends.
+	Shots       int
+	Turns       int
+	WinnerShots int
+}
+
+// Player IDs in the simulated rooms.
+const (
+	IDA = "a"
+	IDB = "b"
+)
+
+// Names of the reported metrics.
+const (
+	MetricShotsToWin = "shots_to_win"
+	MetricGameShots  = "game_shots"
+	// MetricShotsPerTurn is 1 when turns alternate on every shot, and more
+	// when a mode lets a player fire several shots in a turn.
+	MetricShotsPerTurn = "shots_per_turn"
+)
+
+// ErrIllegalMove fails a game in which an agent broke the rules.
+var ErrIllegalMove = errors.New("illegal move")
+
+// Report summarises a series. Failed games, where an agent made an illegal
+// move, count for neither player.
+type Report struct {
+	A, B     string
+	Games    int
+	Failed   int
+	WinsA    int
+	WinsB    int
+	WinRateA float64
+	WinRateB float64
+	Metrics  []balance.Metric
+}
+
+// Play runs a single game to the end. Errors other than ErrIllegalMove mean
+// the game could not be set up.
+func Play(cfg Config, seed int64, first string) (Game, error) {
+	g := Game{Seed: seed, First: first}
+	room := match.NewGameRoom(match.Options{RoomID: "sim", Mode: cfg.Mode, Player1: IDA, Player2: IDB, Seed: seed})
+	for _, p := range []struct {
+		id     string
+		player Player
+	}{{IDA, cfg.A}, {IDB, cfg.B}} {
+		agent, err := p.player.New(room.RNG.Fork())
+		if err != nil {
+			return g, err
+		}
+		if err := room.AddAgent(p.id, agent); err != nil {
+			return g, fmt.Errorf("%w: %s: %v", ErrIllegalMove, p.player.Name, err)
+		}
+	}
+
+	// The room lets an agent whose move it rejects fire at a legal cell
+	// instead; here the first rejection fails the game.
+	var rejected error
+	room.OnRejectedMove = func(playerID string, err error) {
+		if rejected == nil {
+			rejected = fmt.Errorf("%s: %v", playerID, err)
+		}
+	}
+
+	room.Start(first)
+	shots := map[string]int{}
+	err := room.PlayAgents(func(s *match.Shot) {
+		shots[s.Player]++
+		if s.GameOver || s.NextTurn != s.Player {
+			g.Turns++
+		}
+	})
+	if err == nil {
+		err = rejected
+	}
+	if err != nil {
+		return g, fmt.Errorf("%w: %v", ErrIllegalMove, err)
+	}
+	if room.Status != "ended" {
+		return g, errors.New("game did not end")
+	}
+	g.Winner = room.WinnerID
+	g.Shots = shots[IDA] + shots[IDB]
+	g.WinnerShots = shots[g.Winner]
+	return g, nil
+}
+
+// Run plays cfg.Games games and summarises them. Results depend only on the
+// config.
+func Run(cfg Config) (Report, []Game, error) {
+	if cfg.Games <= 0 {
+		return Report{}, nil, errors.New("games must be positive")
+	}
+	r := Report{A: cfg.A.Name, B: cfg.B.Name, Games: cfg.Games}
+	source := rng.New(cfg.Seed)
+	var games []Game
+	var toWin, gameShots, perTurn []float64
+	for i := 0; i < cfg.Games; i++ {
+		first := IDA
+		if i%2 == 1 {
+			first = IDB
+		}
+		g, err := Play(cfg, int64(source.Intn(1<<62))+1, first)
+		if err != nil && !errors.Is(err, ErrIllegalMove) {
+			return Report{}, nil, err
+		}
+		games = append(games, g)
+		if err != nil {
+			r.Failed++
+			continue
+		}
+		if g.Winner == IDA {
+			r.WinsA++
+		} else {
+			r.WinsB++
+		}
+		toWin = append(toWin, float64(g.WinnerShots))
+		gameShots = append(gameShots, float64(g.Shots))
+		perTurn = append(perTurn, float64(g.Shots)/float64(g.Turns))
+	}
+	if played := cfg.Games - r.Failed; played > 0 {
+		r.WinRateA = float64(r.WinsA) / float64(played)
+		r.WinRateB = float64(r.WinsB) / float64(played)
+	}
+	r.Metrics = []balance.Metric{
+		balance.Distribution(MetricShotsToWin, toWin),
+		balance.Distribution(MetricGameShots, gameShots),
+		balance.Distribution(MetricShotsPerTurn, perTurn),
+	}
+	return r, games, nil
+}
+
+// Tournament plays a series between every pair of players, each against
+// each once.
+func Tournament(players []Player, games int, seed int64, mode string) ([]Report, error) {
+	var reports []Report
+	for i := range players {
+		for j := i + 1; j < len(players); j++ {
+			r, _, err := Run(Config{A: players[i], B: players[j], Games: games, Seed: seed, Mode: mode})
+			if err != nil {
+				return nil, err
+			}
+			reports = append(reports, r)
+		}
+	}
+	return reports, nil
+}
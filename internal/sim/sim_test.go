@@ -0,0 +1,101 @@
+package sim
+
+import (
+	"bytes"
+	"lesta-battleship/server-core/internal/bot"
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/match"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	cfg := Config{A: BotPlayer(bot.LevelEasy), B: BotPlayer(bot.LevelHard), Games: 40, Seed: 5}
+	report, games, err := Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 0 || report.WinsA+report.WinsB != cfg.Games {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.WinsB <= report.WinsA {
+		t.Errorf("hard won %d of %d games against easy", report.WinsB, cfg.Games)
+	}
+	for _, g := range games {
+		// Turns alternate on every shot by default.
+		if g.WinnerShots > g.Shots || g.WinnerShots < 20 || g.Turns != g.Shots {
+			t.Errorf("game %+v", g)
+		}
+	}
+	if m := report.Metrics[2]; m.Name != MetricShotsPerTurn || m.Min != 1 || m.Max != 1 {
+		t.Errorf("metric = %+v", m)
+	}
+	if games[0].First != IDA || games[1].First != IDB {
+		t.Error("players do not take turns at moving first")
+	}
+
+	again, _, _ := Run(cfg)
+	if !reflect.DeepEqual(report, again) {
+		t.Error("same config gave different reports")
+	}
+}
+
+// stubborn always fires at the same cell.
+type stubborn struct{ *bot.Bot }
+
+func (stubborn) NextShot() game.Coord { return game.Coord{} }
+
+func TestRun_IllegalMovesFail(t *testing.T) {
+	cheater := Player{Name: "stubborn", New: func(r game.Intn) (match.Agent, error) {
+		b, err := bot.New(bot.LevelEasy, r)
+		return stubborn{b}, err
+	}}
+	report, _, err := Run(Config{A: cheater, B: BotPlayer(bot.LevelEasy), Games: 4, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 4 {
+		t.Errorf("failed = %d, want every game", report.Failed)
+	}
+}
+
+func TestTournament(t *testing.T) {
+	players := []Player{BotPlayer(bot.LevelEasy), BotPlayer(bot.LevelMedium), BotPlayer(bot.LevelHard)}
+	reports, err := Tournament(players, 4, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("got %d series, want 3", len(reports))
+	}
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, reports); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != 1+3*3 {
+		t.Errorf("CSV has %d lines:\n%s", lines, buf.String())
+	}
+
+	if _, err := Tournament([]Player{BotPlayer("nobody"), BotPlayer(bot.LevelEasy)}, 1, 1, ""); err == nil {
+		t.Error("expected an error for an unknown bot level")
+	}
+}
+
+func TestRun_Salvo(t *testing.T) {
+	report, games, err := Run(Config{A: BotPlayer(bot.LevelMedium), B: BotPlayer(bot.LevelMedium), Games: 10, Seed: 2, Mode: match.ModeSalvo})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed != 0 {
+		t.Fatalf("%d games failed", report.Failed)
+	}
+	for _, g := range games {
+		if g.Turns >= g.Shots/2 {
+			t.Errorf("salvo game took %d turns for %d shots", g.Turns, g.Shots)
+		}
+	}
+	if m := report.Metrics[2]; m.Name != MetricShotsPerTurn || m.Min <= 2 {
+		t.Errorf("metric = %+v", m)
+	}
+}
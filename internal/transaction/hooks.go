@@ -0,0 +1,95 @@
+package transaction
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Hook is a set of optional callbacks run around a transaction. BeforeApply
+// may veto a command by returning an error, which rolls the transaction back
+// like a failing command would. OnRollback and OnCommit are not called for
+// dry runs.
+type Hook struct {
+	BeforeApply func(ctx *Context, step int, cmd ContextCommand) error
+	AfterApply  func(ctx *Context, step int, cmd ContextCommand)
+	OnRollback  func(ctx *Context, err error)
+	OnCommit    func(ctx *Context)
+}
+
+type Hooks struct {
+	mu    sync.RWMutex
+	hooks []Hook
+}
+
+func (h *Hooks) Use(hook Hook) {
+	h.mu.Lock()
+	h.hooks = append(h.hooks, hook)
+	h.mu.Unlock()
+}
+
+func (h *Hooks) list() []Hook {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]Hook(nil), h.hooks...)
+}
+
+// Global hooks run for every transaction, before any per-room hooks.
+var Global Hooks
+
+func Use(hook Hook) {
+	Global.Use(hook)
+}
+
+func LoggingHook() Hook {
+	return Hook{
+		OnRollback: func(ctx *Context, err error) {
+			log.Printf("[TX] %s by %s in room %s rolled back after %s: %v", ctx.Action, ctx.PlayerID, ctx.RoomID, ctx.Elapsed(), err)
+		},
+		OnCommit: func(ctx *Context) {
+			log.Printf("[TX] %s by %s in room %s committed in %s", ctx.Action, ctx.PlayerID, ctx.RoomID, ctx.Elapsed())
+		},
+	}
+}
+
+// Histogram counts durations into buckets. Counts[i] holds the observations
+// not greater than Bounds[i]; the last counter holds everything above.
+type Histogram struct {
+	mu     sync.Mutex
+	Bounds []time.Duration
+	Counts []int
+}
+
+func NewHistogram(bounds ...time.Duration) *Histogram {
+	return &Histogram{Bounds: bounds, Counts: make([]int, len(bounds)+1)}
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i, b := range h.Bounds {
+		if d <= b {
+			h.Counts[i]++
+			return
+		}
+	}
+	h.Counts[len(h.Bounds)]++
+}
+
+func (h *Histogram) Snapshot() []int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]int(nil), h.Counts...)
+}
+
+// TimingHook observes the duration of every finished transaction, whether it
+// committed or rolled back.
+func TimingHook(h *Histogram) Hook {
+	return Hook{
+		OnRollback: func(ctx *Context, err error) { h.Observe(ctx.Elapsed()) },
+		OnCommit:   func(ctx *Context) { h.Observe(ctx.Elapsed()) },
+	}
+}
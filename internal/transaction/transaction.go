@@ -1,8 +1,10 @@
 package transaction
 
 import (
+	"errors"
 	"fmt"
 	"lesta-battleship/server-core/internal/game"
+	"time"
 )
 
 type Command interface {
@@ -10,26 +12,200 @@ type Command interface {
 	Undo(gs *game.GameState)
 }
 
+// ContextCommand is a command that may touch both boards of a room at once.
+type ContextCommand interface {
+	Apply(ctx *Context) error
+	Undo(ctx *Context)
+}
+
+type Board int
+
+const (
+	OwnBoard Board = iota
+	EnemyBoard
+)
+
+// Context holds everything a transaction may operate on: the room, the acting
+// player and both boards seen from that player's side.
+type Context struct {
+	RoomID   string
+	PlayerID string
+	Action   string
+	Own      *game.GameState
+	Enemy    *game.GameState
+	DryRun   bool
+
+	started time.Time
+	events  []any
+}
+
+// Emit queues a domain event of the transaction. Events are handed to the
+// OnCommit hooks and dropped if the transaction rolls back. They may be
+// queued before the transaction runs, e.g. to point at its commands.
+func (ctx *Context) Emit(event any) {
+	ctx.events = append(ctx.events, event)
+}
+
+func (ctx *Context) Events() []any {
+	return ctx.events
+}
+
+// Elapsed reports how long the transaction running in ctx has been going.
+func (ctx *Context) Elapsed() time.Duration {
+	if ctx.started.IsZero() {
+		return 0
+	}
+	return time.Since(ctx.started)
+}
+
+func (ctx *Context) State(b Board) (*game.GameState, error) {
+	switch b {
+	case OwnBoard:
+		if ctx.Own == nil {
+			return nil, errors.New("own board is not available")
+		}
+		return ctx.Own, nil
+	case EnemyBoard:
+		if ctx.Enemy == nil {
+			return nil, errors.New("enemy board is not available")
+		}
+		return ctx.Enemy, nil
+	default:
+		return nil, fmt.Errorf("unknown board %d", b)
+	}
+}
+
+// boardCommand binds a single-board command to one side of a Context.
+type boardCommand struct {
+	board Board
+	cmd   Command
+}
+
+func (c *boardCommand) Apply(ctx *Context) error {
+	gs, err := ctx.State(c.board)
+	if err != nil {
+		return err
+	}
+	return c.cmd.Apply(gs)
+}
+
+func (c *boardCommand) Undo(ctx *Context) {
+	gs, err := ctx.State(c.board)
+	if err != nil {
+		return
+	}
+	c.cmd.Undo(gs)
+}
+
 type Transaction struct {
-	commands []Command
+	commands []ContextCommand
+	hooks    *Hooks
 }
 
 func NewTransaction() *Transaction {
 	return &Transaction{}
 }
 
+// WithHooks attaches per-room hooks, run after the global ones.
+func (tx *Transaction) WithHooks(h *Hooks) *Transaction {
+	tx.hooks = h
+	return tx
+}
+
+// Add appends a command that operates on the board passed to Execute (the own
+// board when the transaction is run with ExecuteContext).
 func (tx *Transaction) Add(cmd Command) {
+	tx.AddOn(OwnBoard, cmd)
+}
+
+func (tx *Transaction) AddOn(board Board, cmd Command) {
+	tx.commands = append(tx.commands, &boardCommand{board: board, cmd: cmd})
+}
+
+func (tx *Transaction) AddContext(cmd ContextCommand) {
 	tx.commands = append(tx.commands, cmd)
 }
 
 func (tx *Transaction) Execute(gs *game.GameState) error {
+	return tx.ExecuteContext(&Context{Own: gs})
+}
+
+// ExecuteContext applies all commands in order. If any of them fails, the
+// already applied ones are undone in reverse order, whichever board they
+// touched.
+func (tx *Transaction) ExecuteContext(ctx *Context) error {
+	ctx.started = time.Now()
+	hooks := append(Global.list(), tx.hooks.list()...)
+
 	for i, cmd := range tx.commands {
-		if err := cmd.Apply(gs); err != nil {
+		err := before(hooks, ctx, i, cmd)
+		if err == nil {
+			err = cmd.Apply(ctx)
+		}
+		if err != nil {
 			for j := i - 1; j >= 0; j-- {
-				tx.commands[j].Undo(gs)
+				tx.commands[j].Undo(ctx)
+			}
+			err = fmt.Errorf("error at step %d: %w", i, err)
+			ctx.events = nil
+			if !ctx.DryRun {
+				for _, h := range hooks {
+					if h.OnRollback != nil {
+						h.OnRollback(ctx, err)
+					}
+				}
+			}
+			return err
+		}
+		for _, h := range hooks {
+			if h.AfterApply != nil {
+				h.AfterApply(ctx, i, cmd)
+			}
+		}
+	}
+	if !ctx.DryRun {
+		for _, h := range hooks {
+			if h.OnCommit != nil {
+				h.OnCommit(ctx)
 			}
-			return fmt.Errorf("error at step %d: %w", i, err)
 		}
 	}
 	return nil
 }
+
+func before(hooks []Hook, ctx *Context, step int, cmd ContextCommand) error {
+	for _, h := range hooks {
+		if h.BeforeApply == nil {
+			continue
+		}
+		if err := h.BeforeApply(ctx, step, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tx *Transaction) Validate(gs *game.GameState) error {
+	return tx.ValidateContext(&Context{Own: gs})
+}
+
+// ValidateContext runs the transaction against copies of the boards in ctx
+// and reports whether it would succeed. The real states are never touched,
+// while the commands still carry their would-be results (e.g. a generated
+// ship ID), so callers can preview the outcome.
+func (tx *Transaction) ValidateContext(ctx *Context) error {
+	dry := *ctx
+	dry.DryRun = true
+	dry.events = nil
+	if ctx.Own != nil {
+		dry.Own = ctx.Own.Clone()
+	}
+	if ctx.Enemy != nil {
+		if ctx.Enemy == ctx.Own {
+			dry.Enemy = dry.Own
+		} else {
+			dry.Enemy = ctx.Enemy.Clone()
+		}
+	}
+	return tx.ExecuteContext(&dry)
+}
@@ -0,0 +1,134 @@
+package transaction
+
+import (
+	"errors"
+	"lesta-battleship/server-core/internal/game"
+	"testing"
+)
+
+func TestExecuteContext_RollsBackBothBoards(t *testing.T) {
+	own := game.NewGameState()
+	enemy := game.NewGameState()
+	ctx := &Context{Own: own, Enemy: enemy}
+
+	place := &game.PlaceShipCommand{Ship: game.Ship{Type: game.Submarine, Coords: []game.Coord{{X: 0, Y: 0}}}}
+	shot := &game.ShootCommand{Target: game.Coord{X: 5, Y: 5}}
+	badShot := &game.ShootCommand{Target: game.Coord{X: 10, Y: 10}}
+
+	tx := NewTransaction()
+	tx.AddOn(OwnBoard, place)
+	tx.AddOn(EnemyBoard, shot)
+	tx.AddOn(EnemyBoard, badShot)
+
+	if err := tx.ExecuteContext(ctx); err == nil {
+		t.Fatal("expected error for out of bounds shot")
+	}
+	if len(own.Ships) != 0 || own.Field[0][0] != game.Empty {
+		t.Errorf("own board was not rolled back: %v", own.Field[0][0])
+	}
+	if len(enemy.ShotsMade) != 0 || enemy.Field[5][5] != game.Empty {
+		t.Errorf("enemy board was not rolled back: %v", enemy.Field[5][5])
+	}
+}
+
+func TestExecuteContext_MissingBoard(t *testing.T) {
+	tx := NewTransaction()
+	tx.AddOn(EnemyBoard, &game.ShootCommand{Target: game.Coord{X: 1, Y: 1}})
+	if err := tx.Execute(game.NewGameState()); err == nil {
+		t.Error("expected error when enemy board is not in context")
+	}
+}
+
+func TestValidate_DoesNotMutateState(t *testing.T) {
+	gs := game.NewGameState()
+	cmd := &game.PlaceShipCommand{Ship: game.Ship{Type: game.Destroyer, Coords: []game.Coord{{X: 2, Y: 2}, {X: 2, Y: 3}}}}
+	tx := NewTransaction()
+	tx.Add(cmd)
+
+	if err := tx.Validate(gs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Ship.ID == "" {
+		t.Error("expected would-be ship ID to be filled in")
+	}
+	if len(gs.Ships) != 0 || gs.Field[2][2] != game.Empty {
+		t.Error("validate mutated the real state")
+	}
+
+	bad := NewTransaction()
+	bad.Add(&game.PlaceShipCommand{Ship: game.Ship{Type: game.Destroyer, Coords: []game.Coord{{X: 9, Y: 9}, {X: 9, Y: 10}}}})
+	if err := bad.Validate(gs); err == nil {
+		t.Error("expected out of bounds placement to be invalid")
+	}
+}
+
+func TestHooks_VetoAndCallbacks(t *testing.T) {
+	var hooks Hooks
+	var applied, committed, rolledBack int
+	hooks.Use(Hook{
+		BeforeApply: func(ctx *Context, step int, cmd ContextCommand) error {
+			if ctx.Action == "forbidden" {
+				return errors.New("vetoed")
+			}
+			return nil
+		},
+		AfterApply: func(ctx *Context, step int, cmd ContextCommand) { applied++ },
+		OnRollback: func(ctx *Context, err error) { rolledBack++ },
+		OnCommit:   func(ctx *Context) { committed++ },
+	})
+
+	gs := game.NewGameState()
+	tx := NewTransaction().WithHooks(&hooks)
+	tx.Add(&game.ShootCommand{Target: game.Coord{X: 1, Y: 1}})
+	if err := tx.ExecuteContext(&Context{Own: gs, Action: "fire"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vetoed := NewTransaction().WithHooks(&hooks)
+	vetoed.Add(&game.ShootCommand{Target: game.Coord{X: 2, Y: 2}})
+	if err := vetoed.ExecuteContext(&Context{Own: gs, Action: "forbidden"}); err == nil {
+		t.Fatal("expected hook to veto the transaction")
+	}
+	if gs.Field[2][2] != game.Empty {
+		t.Error("vetoed command was applied")
+	}
+
+	dry := NewTransaction().WithHooks(&hooks)
+	dry.Add(&game.ShootCommand{Target: game.Coord{X: 3, Y: 3}})
+	if err := dry.ValidateContext(&Context{Own: gs, Action: "fire"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if applied != 2 || committed != 1 || rolledBack != 1 {
+		t.Errorf("applied=%d committed=%d rolledBack=%d", applied, committed, rolledBack)
+	}
+}
+
+func TestEvents_OnlyReachCommitHooks(t *testing.T) {
+	var hooks Hooks
+	var published []any
+	hooks.Use(Hook{OnCommit: func(ctx *Context) { published = append(published, ctx.Events()...) }})
+
+	gs := game.NewGameState()
+	ok := NewTransaction().WithHooks(&hooks)
+	ok.Add(&game.ShootCommand{Target: game.Coord{X: 1, Y: 1}})
+	ctx := &Context{Own: gs}
+	ctx.Emit("shot")
+	if err := ok.ExecuteContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := NewTransaction().WithHooks(&hooks)
+	failed.Add(&game.ShootCommand{Target: game.Coord{X: 1, Y: 1}})
+	ctx = &Context{Own: gs}
+	ctx.Emit("shot again")
+	if err := failed.ExecuteContext(ctx); err == nil {
+		t.Fatal("expected a second shot at the same cell to fail")
+	}
+	if len(ctx.Events()) != 0 {
+		t.Error("a rolled back transaction kept its events")
+	}
+	if len(published) != 1 || published[0] != "shot" {
+		t.Errorf("published = %v", published)
+	}
+}
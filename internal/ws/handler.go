@@ -2,9 +2,9 @@ package ws
 
 import (
 	"encoding/json"
+	"errors"
 	"lesta-battleship/server-core/internal/game"
 	"lesta-battleship/server-core/internal/match"
-	"lesta-battleship/server-core/internal/transaction"
 	"log"
 	"net/http"
 
@@ -35,6 +35,12 @@ func WebSocketHandler(c *gin.Context) {
 	room := rawRoom.(*match.GameRoom)
 
 	var player *match.PlayerConn
+	if p := room.Player(playerID); p != nil && p.Agent != nil {
+		log.Println("[WS] Player is a bot:", playerID)
+		send(conn, "error", match.ErrHasAgent.Error())
+		conn.Close()
+		return
+	}
 	if room.Player1.ID == playerID {
 		player = room.Player1
 		room.Player1.Conn = conn
@@ -56,12 +62,7 @@ func WebSocketHandler(c *gin.Context) {
 			break
 		}
 
-		var input struct {
-			Event string    `json:"event"`
-			Ship  game.Ship `json:"ship"`
-			X     int       `json:"x"`
-			Y     int       `json:"y"`
-		}
+		var input message
 		_ = json.Unmarshal(msg, &input)
 
 		log.Printf("[WS] Event received from %s: %s\n", playerID, input.Event)
@@ -70,35 +71,11 @@ func WebSocketHandler(c *gin.Context) {
 
 		case "place_ship":
 			room.Mutex.Lock()
-
-			if len(player.State.Ships) >= 10 {
-				room.Mutex.Unlock()
-				send(conn, "place_ship_error", "maximum 10 ships allowed")
-				continue
+			if _, err := room.PlaceShip(playerID, input.Ship); err != nil {
+				sendError(conn, "place_ship_error", err)
 			}
-
-			// Command with Ship from client (without ID)
-			cmd := &game.PlaceShipCommand{Ship: input.Ship}
-			tx := transaction.NewTransaction()
-			tx.Add(cmd)
-
-			err := tx.Execute(player.State)
-
-			// Get the auto-generated ID from GameState after placement
-			shipID := cmd.Ship.ID
-			shipType := cmd.Ship.Type
-
 			room.Mutex.Unlock()
 
-			if err != nil {
-				send(conn, "place_ship_error", err.Error())
-			} else {
-				send(conn, "ship_placed", map[string]any{
-					"ship_id":   shipID,
-					"ship_type": shipType,
-				})
-			}
-
 		case "ready":
 			room.Mutex.Lock()
 
@@ -114,8 +91,7 @@ func WebSocketHandler(c *gin.Context) {
 			shouldStart := false
 
 			if allReady && room.Status == "waiting" {
-				room.Status = "playing"
-				room.Turn = room.Player1.ID
+				room.Start(room.Player1.ID)
 				shouldStart = true
 			}
 			room.Mutex.Unlock()
@@ -125,101 +101,145 @@ func WebSocketHandler(c *gin.Context) {
 			if shouldStart {
 				log.Printf("[WS] Game started in room %s. First turn: %s\n", roomID, room.Turn)
 				broadcast(room, "game_start", gin.H{"first_turn": room.Turn})
+
+				room.Mutex.Lock()
+				playAgents(room)
+				room.Mutex.Unlock()
 			}
 
 		case "remove_ship":
 			room.Mutex.Lock()
+			if err := room.RemoveShip(playerID, input.Ship.ID); err != nil {
+				sendError(conn, "remove_ship_error", err)
+			}
+			room.Mutex.Unlock()
 
-			if player.Ready {
-				room.Mutex.Unlock()
-				send(conn, "remove_ship_error", "you cannot remove ship after ready")
-				break
+		case "fire":
+			room.Mutex.Lock()
+			log.Printf("[FIRE] %s firing at (%d,%d)", playerID, input.X, input.Y)
+			if _, err := room.Fire(playerID, game.Coord{X: input.X, Y: input.Y}); err != nil {
+				log.Println("[FIRE] Error:", err)
+				sendError(conn, "fire_error", err)
 			}
+			playAgents(room)
+			room.Mutex.Unlock()
 
-			shipID := input.Ship.ID
-			if shipID == "" {
-				room.Mutex.Unlock()
-				send(conn, "remove_ship_error", "missing ship ID")
-				break
+		case "fire_salvo":
+			room.Mutex.Lock()
+			log.Printf("[FIRE] %s firing a salvo at %v", playerID, input.Targets)
+			if _, err := room.FireSalvo(playerID, input.Targets); err != nil {
+				log.Println("[FIRE] Error:", err)
+				sendError(conn, "fire_salvo_error", err)
 			}
+			playAgents(room)
+			room.Mutex.Unlock()
 
-			cmd := &game.RemoveShipCommand{ShipID: shipID}
-			tx := transaction.NewTransaction()
-			tx.Add(cmd)
+		case "use_item":
+			useItem(room, player, input)
 
-			err := tx.Execute(player.State)
+		case "get_inventory":
+			room.Mutex.Lock()
+			slots := player.Inventory.Slots()
 			room.Mutex.Unlock()
 
-			if err != nil {
-				send(conn, "remove_ship_error", err.Error())
-			} else {
-				send(conn, "ship_removed", map[string]string{"ship_id": shipID})
-			}
+			send(conn, "inventory", gin.H{"items": slots})
 
-		case "fire":
+		case "validate_place_ship", "validate_remove_ship", "validate_fire", "validate_fire_salvo", "validate_use_item":
 			room.Mutex.Lock()
-			log.Printf("[FIRE] %s firing at (%d,%d)", playerID, input.X, input.Y)
+			result := validate(room, player, input)
+			room.Mutex.Unlock()
 
-			if room.Status != "playing" {
-				send(conn, "error", "game not started")
-				room.Mutex.Unlock()
-				continue
-			}
+			send(conn, "validate_result", result)
+		}
+	}
+}
 
-			if room.Turn != playerID {
-				send(conn, "not_your_turn", nil)
-				room.Mutex.Unlock()
-				continue
-			}
+type message struct {
+	Event  string                 `json:"event"`
+	Ship   game.Ship              `json:"ship"`
+	X      int                    `json:"x"`
+	Y      int                    `json:"y"`
+	ItemID int                    `json:"item_id"`
+	Params map[string]interface{} `json:"params"`
+	// Targets are the cells of a fire_salvo.
+	Targets []game.Coord `json:"targets"`
+}
 
-			var target *match.PlayerConn
-			if room.Player1.ID == playerID {
-				target = room.Player2
-			} else {
-				target = room.Player1
-			}
+// Attach forwards the events of a room to its websocket clients.
+func Attach(room *match.GameRoom) {
+	room.Subscribe(func(e match.Event) { forward(room, e) })
+}
 
-			cmd := &game.ShootCommand{Target: game.Coord{X: input.X, Y: input.Y}}
-			tx := transaction.NewTransaction()
-			tx.Add(cmd)
-			err := tx.Execute(target.State)
-			if err != nil {
-				log.Println("[FIRE] Error:", err)
-				send(conn, "fire_error", err.Error())
-				room.Mutex.Unlock()
-				continue
-			}
+func forward(room *match.GameRoom, e match.Event) {
+	player := room.Player(e.Player)
+	switch e.Kind {
+	case match.EventShipPlaced:
+		ship := e.Value.(game.Ship)
+		sendTo(player, "ship_placed", gin.H{"ship_id": ship.ID, "ship_type": ship.Type})
+	case match.EventShipRemoved:
+		sendTo(player, "ship_removed", gin.H{"ship_id": e.Value.(game.Ship).ID})
+	case match.EventShot:
+		shot := e.Value.(*match.Shot)
+		result := shotResult(shot)
+		if room.Rules.Salvo {
+			result["shots_left"] = shot.ShotsLeft
+		}
+		broadcast(room, "fire_result", result)
+	case match.EventSalvo:
+		salvo := e.Value.(*match.Salvo)
+		shots := make([]gin.H, len(salvo.Shots))
+		for i := range salvo.Shots {
+			shots[i] = shotResult(&salvo.Shots[i])
+		}
+		broadcast(room, "salvo_result", gin.H{
+			"player":    salvo.Player,
+			"shots":     shots,
+			"next_turn": salvo.NextTurn,
+			"game_over": salvo.GameOver,
+		})
+	case match.EventItemUsed:
+		sendItemUse(room, player, e.Value.(*match.ItemUse))
+	case match.EventGameEnd:
+		broadcast(room, "game_end", gin.H{"winner": e.Value})
+	}
+}
 
-			// Check if all ships destroyed
-			shipsLeft := 0
-			for _, s := range target.State.Ships {
-				for _, coord := range s.Coords {
-					if target.State.Field[coord.X][coord.Y] == game.ShipCell {
-						shipsLeft++
-					}
-				}
-			}
+func shotResult(shot *match.Shot) gin.H {
+	result := gin.H{
+		"x":         shot.Target.X,
+		"y":         shot.Target.Y,
+		"hit":       shot.Hit,
+		"next_turn": shot.NextTurn,
+		"game_over": shot.GameOver,
+	}
+	if shot.Sonar != nil {
+		result["sonar"] = *shot.Sonar
+	}
+	return result
+}
 
-			gameOver := shipsLeft == 0
-			log.Printf("[FIRE] hit=%v gameOver=%v", target.State.Field[input.X][input.Y] == game.Hit, gameOver)
-
-			broadcast(room, "fire_result", gin.H{
-				"x":         input.X,
-				"y":         input.Y,
-				"hit":       target.State.Field[input.X][input.Y] == game.Hit,
-				"next_turn": target.ID,
-				"game_over": gameOver,
-			})
-
-			if gameOver {
-				room.Status = "ended"
-				room.WinnerID = playerID
-				broadcast(room, "game_end", gin.H{"winner": playerID})
-			} else {
-				room.Turn = target.ID
-			}
-			room.Mutex.Unlock()
-		}
+// playAgents lets the bots of the room take their turns; their shots reach
+// the clients as room events. The caller must hold the room mutex.
+func playAgents(room *match.GameRoom) {
+	if err := room.PlayAgents(nil); err != nil {
+		log.Println("[BOT] Error:", err)
+	}
+}
+
+// sendError reports a failed transaction. Room rule violations keep their own
+// events and messages; everything else goes out as errEvent.
+func sendError(conn *websocket.Conn, errEvent string, err error) {
+	switch {
+	case errors.Is(err, match.ErrNotYourTurn):
+		send(conn, "not_your_turn", nil)
+	case errors.Is(err, match.ErrGameNotStarted):
+		send(conn, "error", match.ErrGameNotStarted.Error())
+	case errors.Is(err, match.ErrTooManyShips):
+		send(conn, errEvent, match.ErrTooManyShips.Error())
+	case errors.Is(err, match.ErrAlreadyReady):
+		send(conn, errEvent, match.ErrAlreadyReady.Error())
+	default:
+		send(conn, errEvent, err.Error())
 	}
 }
 
@@ -233,6 +253,16 @@ func send(conn *websocket.Conn, event string, data any) {
 	}
 }
 
+func sendTo(p *match.PlayerConn, event string, data any) {
+	raw, _ := json.Marshal(map[string]any{
+		"event": event,
+		"data":  data,
+	})
+	if err := p.WriteMessage(websocket.TextMessage, raw); err != nil {
+		log.Println("[WS] Send failed:", err)
+	}
+}
+
 func broadcast(room *match.GameRoom, event string, data any) {
 	msg := map[string]any{
 		"event": event,
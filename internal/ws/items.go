@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"lesta-battleship/server-core/internal/items"
+	"lesta-battleship/server-core/internal/match"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Catalogue serves the items players can use. It is set on startup.
+var Catalogue = items.NewCache(items.MemoryProvider(nil))
+
+// useItem handles the use_item event; the outcome reaches the clients as a
+// room event.
+func useItem(room *match.GameRoom, player *match.PlayerConn, input message) {
+	room.Mutex.Lock()
+	defer room.Mutex.Unlock()
+
+	log.Printf("[ITEM] %s uses item %d with %v", player.ID, input.ItemID, input.Params)
+	if _, err := room.UseItem(player.ID, input.ItemID, Catalogue.Items(), input.Params); err != nil {
+		log.Println("[ITEM] Error:", err)
+		sendError(player.Conn, "use_item_error", err)
+		return
+	}
+	playAgents(room)
+}
+
+// sendItemUse sends the item_result to the user, who always gets the effect
+// log, and item_used to the opponent, who only sees the effects on their own
+// board.
+func sendItemUse(room *match.GameRoom, player *match.PlayerConn, use *match.ItemUse) {
+	sendTo(player, "item_result", gin.H{
+		"item_id":   use.Item.ID,
+		"effects":   use.Result.Effects,
+		"ends_turn": use.Result.EndsTurn,
+		"next_turn": use.NextTurn,
+		"game_over": use.GameOver,
+		"inventory": player.Inventory.Slots(),
+	})
+	seen := gin.H{
+		"item_id":   use.Item.ID,
+		"player":    player.ID,
+		"next_turn": use.NextTurn,
+		"game_over": use.GameOver,
+	}
+	var effects []items.Effect
+	for _, e := range use.Result.Effects {
+		if !e.Own {
+			effects = append(effects, e)
+		}
+	}
+	if len(effects) > 0 {
+		seen["effects"] = effects
+	}
+	sendTo(room.Opponent(player.ID), "item_used", seen)
+}
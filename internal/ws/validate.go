@@ -0,0 +1,57 @@
+package ws
+
+import (
+	"lesta-battleship/server-core/internal/game"
+	"lesta-battleship/server-core/internal/match"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validate runs the requested action as a dry run and reports whether it
+// would succeed, without touching the real boards. The caller must hold the
+// room mutex.
+func validate(room *match.GameRoom, player *match.PlayerConn, input message) gin.H {
+	action := strings.TrimPrefix(input.Event, "validate_")
+	result := gin.H{"action": action, "valid": true}
+	fail := func(err error) gin.H {
+		result["valid"] = false
+		result["error"] = err.Error()
+		return result
+	}
+
+	switch action {
+	case "place_ship":
+		ship, err := room.ValidatePlaceShip(player.ID, input.Ship)
+		if err != nil {
+			return fail(err)
+		}
+		result["ship_id"] = ship.ID
+		result["ship_type"] = ship.Type
+
+	case "remove_ship":
+		if err := room.ValidateRemoveShip(player.ID, input.Ship.ID); err != nil {
+			return fail(err)
+		}
+		result["ship_id"] = input.Ship.ID
+
+	case "fire":
+		// Never report whether the shot would hit: that would reveal the
+		// enemy board for free.
+		if err := room.ValidateFire(player.ID, game.Coord{X: input.X, Y: input.Y}); err != nil {
+			return fail(err)
+		}
+
+	case "fire_salvo":
+		if err := room.ValidateSalvo(player.ID, input.Targets); err != nil {
+			return fail(err)
+		}
+
+	case "use_item":
+		if err := room.ValidateItem(player.ID, input.ItemID, Catalogue.Items(), input.Params); err != nil {
+			return fail(err)
+		}
+		result["item_id"] = input.ItemID
+	}
+	return result
+}